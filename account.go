@@ -0,0 +1,177 @@
+package dominocount
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// sessionCookie holds the session token of a logged in user.
+const sessionCookie = "session"
+
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// accountForm is the data of the signup and login forms.
+type accountForm struct {
+	Name  string
+	Error string
+}
+
+// withUser attaches the user of the session cookie, if any, to the request
+// context. A broken session is treated as anonymous.
+func (s server) withUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookie)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		u, err := s.store.GetUserBySession(cookie.Value)
+		if err != nil {
+			fmt.Fprintln(s.output, err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if u.ID != 0 {
+			r = r.WithContext(context.WithValue(r.Context(), userContextKey, u))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// currentUser returns the logged in user, nil for anonymous requests.
+func currentUser(r *http.Request) *user {
+	u, _ := r.Context().Value(userContextKey).(*user)
+	return u
+}
+
+// requireUser answers 401 to anonymous requests, it reports whether a user
+// is logged in.
+func requireUser(w http.ResponseWriter, r *http.Request) bool {
+	if currentUser(r) != nil {
+		return true
+	}
+	http.Error(w, "log in to continue", http.StatusUnauthorized)
+	return false
+}
+
+// currentUserID returns the ID of the logged in user, 0 for anonymous
+// requests.
+func currentUserID(r *http.Request) int64 {
+	u := currentUser(r)
+	if u == nil {
+		return 0
+	}
+	return u.ID
+}
+
+// HandleSignup renders the signup form on GET and creates the account, logged
+// in right away, on POST.
+func (s server) HandleSignup() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			render(w, r, signupTemplate, accountForm{})
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+
+		form := accountForm{Name: r.PostFormValue("name")}
+		u, err := s.store.CreateUser(form.Name, r.PostFormValue("password"))
+		if err != nil {
+			switch err.(type) {
+			case *UserExistsError:
+				w.WriteHeader(http.StatusConflict)
+				form.Error = "ese nombre ya está en uso"
+			case *PasswordTooLongError:
+				w.WriteHeader(http.StatusBadRequest)
+				form.Error = fmt.Sprintf("la contraseña no puede pasar de %d caracteres", MaxPasswordLength)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				form.Error = fmt.Sprintf("nombre requerido y contraseña de al menos %d caracteres", MinPasswordLength)
+			}
+			render(w, r, signupTemplate, form)
+			return
+		}
+		s.startSession(w, r, u)
+	}
+}
+
+// HandleLogin renders the login form on GET and logs the user in on POST.
+func (s server) HandleLogin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			render(w, r, loginTemplate, accountForm{})
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+
+		form := accountForm{Name: r.PostFormValue("name")}
+		u, err := s.store.AuthenticateUser(form.Name, r.PostFormValue("password"))
+		if err != nil {
+			_, ok := err.(*InvalidCredentialsError)
+			if !ok {
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			form.Error = "nombre o contraseña incorrectos"
+			render(w, r, loginTemplate, form)
+			return
+		}
+		s.startSession(w, r, u)
+	}
+}
+
+// HandleLogout ends the session and sends the browser home.
+func (s server) HandleLogout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		cookie, err := r.Cookie(sessionCookie)
+		if err == nil {
+			err = s.store.DeleteSession(cookie.Value)
+			if err != nil {
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+		setSessionCookie(w, r, "", -1)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+func (s server) startSession(w http.ResponseWriter, r *http.Request, u *user) {
+	token, err := s.store.CreateSession(u.ID)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	setSessionCookie(w, r, token, int(sessionDuration/time.Second))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// setSessionCookie sets the session cookie, a negative maxAge deletes it. The
+// cookie is only sent over HTTPS when the request came that way, directly or
+// through the proxy in front of the app.
+func setSessionCookie(w http.ResponseWriter, r *http.Request, token string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
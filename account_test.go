@@ -0,0 +1,240 @@
+package dominocount_test
+
+import (
+	"dominocount"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newAccountTestClient(t *testing.T) (*http.Client, *httptest.Server, dominocount.Storage) {
+	t.Helper()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testServer := httptest.NewServer(server.Routes())
+	t.Cleanup(testServer.Close)
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Jar: jar}, testServer, &store
+}
+
+func TestSignupLoginAndLogout(t *testing.T) {
+	t.Parallel()
+	client, testServer, _ := newAccountTestClient(t)
+	account := url.Values{"name": {"Ana"}, "password": {"secreta123"}}
+
+	res, err := client.PostForm(testServer.URL+"/signup", account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "salir (Ana)") {
+		t.Fatalf("want Ana logged in after signup\nGot:\n%s", body)
+	}
+	session := client.Jar.Cookies(res.Request.URL)
+	if len(session) != 1 || session[0].Name != "session" {
+		t.Fatalf("want a session cookie, got %+v", session)
+	}
+
+	res, err = client.PostForm(testServer.URL+"/signup", account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusConflict {
+		t.Errorf("want status 409 signing up a taken name, got %d", res.StatusCode)
+	}
+
+	res, err = client.PostForm(testServer.URL+"/signup", url.Values{"name": {"Luis"}, "password": {strings.Repeat("a", 73)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "no puede pasar de 72") {
+		t.Errorf("want a too long password told apart, got %d\n%s", res.StatusCode, body)
+	}
+
+	res, err = client.PostForm(testServer.URL+"/logout", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "entrar") {
+		t.Errorf("want login link after logout\nGot:\n%s", body)
+	}
+
+	res, err = client.PostForm(testServer.URL+"/login", url.Values{"name": {"Ana"}, "password": {"equivocada"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("want status 401 with a wrong password, got %d", res.StatusCode)
+	}
+	res, err = client.PostForm(testServer.URL+"/login", account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "salir (Ana)") {
+		t.Errorf("want Ana logged in again\nGot:\n%s", body)
+	}
+}
+
+func TestLoggedInUserOwnsAndEditsMatches(t *testing.T) {
+	t.Parallel()
+	client, testServer, store := newAccountTestClient(t)
+
+	res, err := client.PostForm(testServer.URL+"/signup", url.Values{"name": {"Ana"}, "password": {"secreta123"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	res, err = client.PostForm(testServer.URL+"/match/", url.Values{"team1_name": {"mío"}, "team2_name": {"bar"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	slug := res.Request.URL.Path[len("/match/"):]
+	other := dominocount.NewMatch(dominocount.MatchWithTeam1Name("ajeno"))
+	err = store.CreateMatch(&other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = client.Get(testServer.URL + "/matches?mine=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "mío") || strings.Contains(string(body), "ajeno") {
+		t.Errorf("want only Ana's matches listed\nGot:\n%s", body)
+	}
+
+	// the owner edits without the edit secret cookie set on creation
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverURL, err := url.Parse(testServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cookie := range client.Jar.Cookies(serverURL) {
+		if cookie.Name == "session" {
+			jar.SetCookies(serverURL, []*http.Cookie{cookie})
+		}
+	}
+	client.Jar = jar
+	req, err := http.NewRequest(http.MethodPatch, testServer.URL+"/match/"+slug, strings.NewReader("team1_points=20&team2_points=0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("want the owner allowed to add points, got %d", res.StatusCode)
+	}
+
+	req, err = http.NewRequest(http.MethodPatch, testServer.URL+"/match/"+other.Slug, strings.NewReader("team1_points=20&team2_points=0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("want status 403 editing someone else's match, got %d", res.StatusCode)
+	}
+}
+
+func TestOnlyLoggedInUsersChangePlayers(t *testing.T) {
+	t.Parallel()
+	client, testServer, store := newAccountTestClient(t)
+	rosa, err := store.CreatePlayer("Rosa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	luis, err := store.CreatePlayer("Luis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rename := func() *http.Response {
+		req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/players/%d", testServer.URL, rosa.ID), strings.NewReader("name=Rosita"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res, err := client.PostForm(testServer.URL+"/players", url.Values{"name": {"Ana"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("want status 401 creating a player anonymously, got %d", res.StatusCode)
+	}
+	if res = rename(); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("want status 401 renaming a player anonymously, got %d", res.StatusCode)
+	}
+	merge := fmt.Sprintf("%s/players/%d/merge", testServer.URL, luis.ID)
+	res, err = client.PostForm(merge, url.Values{"into": {fmt.Sprint(rosa.ID)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("want status 401 merging players anonymously, got %d", res.StatusCode)
+	}
+
+	_, err = client.PostForm(testServer.URL+"/signup", url.Values{"name": {"Ana"}, "password": {"secreta123"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res = rename(); res.StatusCode != http.StatusNoContent {
+		t.Errorf("want status 204 renaming a player logged in, got %d", res.StatusCode)
+	}
+	players, err := store.ListPlayers("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 2 || (players[0].Name != "Rosita" && players[1].Name != "Rosita") {
+		t.Errorf("want Rosa renamed and no player created anonymously, got %+v", players)
+	}
+}
@@ -0,0 +1,319 @@
+package dominocount
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MarshalJSON adds the finish and archive timestamps only when they are set.
+func (m match) MarshalJSON() ([]byte, error) {
+	type plainMatch match
+	return json.Marshal(struct {
+		plainMatch
+		FinishedAt *time.Time `json:"finished_at,omitempty"`
+		ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	}{
+		plainMatch: plainMatch(m),
+		FinishedAt: timeOrNil(m.FinishedAt),
+		ArchivedAt: timeOrNil(m.ArchivedAt),
+	})
+}
+
+// MarshalJSON writes a rule set as its name.
+func (r rules) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.name)
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// apiMatchRequest is the body to create a match through the API. Players are
+// keyed by seat.
+type apiMatchRequest struct {
+	Team1       string          `json:"team1"`
+	Team2       string          `json:"team2"`
+	RuleSet     string          `json:"rule_set"`
+	TargetScore int             `json:"target_score"`
+	Players     map[seat]string `json:"players"`
+}
+
+// apiHandRequest is the body to add a hand through the API.
+type apiHandRequest struct {
+	Points1 int `json:"team1_points"`
+	Points2 int `json:"team2_points"`
+	Bonuses []struct {
+		Team team   `json:"team"`
+		Kind string `json:"kind"`
+	} `json:"bonuses"`
+	Domino string `json:"domino"`
+}
+
+type apiMatchList struct {
+	Matches    []match `json:"matches"`
+	NextCursor int64   `json:"next_cursor,omitempty"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// HandleOpenAPI serves the OpenAPI document describing the routes.
+func (s server) HandleOpenAPI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not supported"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPISpec)
+	}
+}
+
+// HandleAPIMatches lists matches on GET, with the same filters as the match
+// listing page, and creates a match on POST.
+func (s server) HandleAPIMatches() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			filter, err := queryStringParseMatchFilter(r)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, err)
+				return
+			}
+			matches, next, err := s.store.ListMatches(filter)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, apiMatchList{Matches: matches, NextCursor: next})
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			s.handleAPICreateMatch(w, r)
+			return
+		}
+
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not supported"))
+	}
+}
+
+// HandleAPIMatch returns a single match with its hands.
+func (s server) HandleAPIMatch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not supported"))
+			return
+		}
+		m, ok := s.apiGetMatch(w, r)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, m)
+	}
+}
+
+// HandleAPIHands lists the hands of a match on GET and plays a new hand on
+// POST, returning the updated match.
+func (s server) HandleAPIHands() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			m, ok := s.apiGetMatch(w, r)
+			if !ok {
+				return
+			}
+			writeJSON(w, http.StatusOK, m.Hands)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			s.handleAPIAddHand(w, r)
+			return
+		}
+
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not supported"))
+	}
+}
+
+func (s server) handleAPICreateMatch(w http.ResponseWriter, r *http.Request) {
+	req := apiMatchRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, errors.New("not able to parse body"))
+		return
+	}
+	if req.TargetScore < 0 {
+		writeJSONError(w, http.StatusBadRequest, errors.New("target score must be positive"))
+		return
+	}
+
+	opts := []matchOption{
+		MatchWithTeam1Name(req.Team1),
+		MatchWithTeam2Name(req.Team2),
+		MatchWithTargetScore(req.TargetScore),
+		MatchWithOwner(currentUserID(r)),
+	}
+	if req.RuleSet != "" {
+		rules, err := RuleSetByName(req.RuleSet)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		opts = append(opts, MatchWithRuleSet(rules))
+	}
+	for st, name := range req.Players {
+		_, err := parseSeat(string(st))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		if name != "" && strings.TrimSpace(name) == "" {
+			writeJSONError(w, http.StatusBadRequest, errors.New("player name cannot be empty"))
+			return
+		}
+	}
+	for _, st := range allSeats {
+		opts = append(opts, MatchWithPlayer(st, req.Players[st]))
+	}
+
+	m := NewMatch(opts...)
+	err = s.store.CreateMatch(&m)
+	if _, ok := err.(*PlayersShareMatchError); ok {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/matches/"+m.Slug)
+	w.Header().Set("X-Edit-Secret", m.EditSecret)
+	writeJSON(w, http.StatusCreated, m)
+}
+
+// handleAPIAddHand records a hand, the request must come from the owner of
+// the match or carry the edit secret returned when the match was created in
+// the X-Edit-Secret header.
+func (s server) handleAPIAddHand(w http.ResponseWriter, r *http.Request) {
+	id, err := s.queryStringParseMatchID(r)
+	if err != nil {
+		writeJSONError(w, apiErrorStatus(err), err)
+		return
+	}
+	err = s.store.CheckEditAccess(id, editSecret(r), currentUserID(r))
+	if err != nil {
+		writeJSONError(w, apiErrorStatus(err), err)
+		return
+	}
+	req := apiHandRequest{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, errors.New("not able to parse body"))
+		return
+	}
+	if req.Points1 < 0 || req.Points2 < 0 {
+		writeJSONError(w, http.StatusBadRequest, errors.New("points cannot be negative"))
+		return
+	}
+
+	h := NewHand(req.Points1, req.Points2)
+	for _, b := range req.Bonuses {
+		kind, err := parseBonus(b.Kind)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		if b.Team != Team1 && b.Team != Team2 {
+			writeJSONError(w, http.StatusBadRequest, errors.New("unknown team "+string(b.Team)))
+			return
+		}
+		h = h.WithBonus(b.Team, kind)
+	}
+	if req.Domino != "" {
+		st, err := parseSeat(req.Domino)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		h = h.WonBy(st)
+	}
+
+	m, err := s.store.AddHandByID(id, h)
+	if err != nil {
+		writeJSONError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, m)
+}
+
+// apiGetMatch loads the match of the request, on failure the error has been
+// written and ok is false.
+func (s server) apiGetMatch(w http.ResponseWriter, r *http.Request) (m *match, ok bool) {
+	id, err := s.queryStringParseMatchID(r)
+	if err != nil {
+		writeJSONError(w, apiErrorStatus(err), err)
+		return nil, false
+	}
+	m, err = s.store.GetMatchByID(id)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if m.Id == 0 {
+		writeJSONError(w, http.StatusNotFound, &MatchNotFoundError{})
+		return nil, false
+	}
+	return m, true
+}
+
+// apiErrorStatus maps store errors to HTTP statuses.
+func apiErrorStatus(err error) int {
+	switch err.(type) {
+	case *MatchNotFoundError, *HandNotFoundError, *PlayerNotFoundError:
+		return http.StatusNotFound
+	case *GameOverError, *MatchArchivedError, *PlayerExistsError:
+		return http.StatusConflict
+	case *EditForbiddenError:
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		fmt.Fprintln(w, err)
+	}
+}
+
+// writeJSONError writes err as the body, except for internal errors whose
+// text is not for clients.
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	if status == http.StatusInternalServerError {
+		err = errors.New("internal server error")
+	}
+	writeJSON(w, status, apiError{Error: err.Error()})
+}
+
+// acceptsJSON reports whether the client prefers JSON over HTML.
+func acceptsJSON(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(accept), ";")
+		switch mediaType {
+		case "application/json":
+			return true
+		case "text/html":
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+package dominocount_test
+
+import (
+	"dominocount"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type apiMatch struct {
+	ID      string `json:"id"`
+	Team1   string `json:"team1"`
+	Score1  int    `json:"score1"`
+	Score2  int    `json:"score2"`
+	RuleSet string `json:"rule_set"`
+	Status  string `json:"status"`
+	Players []struct {
+		Name string `json:"name"`
+		Seat string `json:"seat"`
+	} `json:"players"`
+	Hands []struct {
+		Number  int `json:"number"`
+		Points2 int `json:"team2_points"`
+	} `json:"hands"`
+}
+
+func newAPITestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testServer := httptest.NewServer(server.Routes())
+	t.Cleanup(testServer.Close)
+	return testServer
+}
+
+func TestAPI_CreateMatchAndPlayHand(t *testing.T) {
+	t.Parallel()
+	testServer := newAPITestServer(t)
+
+	body := `{"team1":"foo","team2":"bar","rule_set":"dominicano","players":{"north":"Ana","east":"Luis"}}`
+	res, err := http.Post(testServer.URL+"/api/v1/matches", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("want status 201 Created, got %d", res.StatusCode)
+	}
+	created := apiMatch{}
+	err = json.NewDecoder(res.Body).Decode(&created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" || created.Team1 != "foo" || created.RuleSet != "dominicano" || len(created.Players) != 2 {
+		t.Fatalf("want created match returned, got %+v", created)
+	}
+
+	secret := res.Header.Get("X-Edit-Secret")
+	if secret == "" {
+		t.Fatal("want the edit secret returned on creation")
+	}
+
+	handsURL := fmt.Sprintf("%s/api/v1/matches/%s/hands", testServer.URL, created.ID)
+	hand := `{"team1_points":0,"team2_points":30,"bonuses":[{"team":"Team2","kind":"capicua"}],"domino":"east"}`
+	res, err = http.Post(handsURL, "application/json", strings.NewReader(hand))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("want hands without the edit secret refused with 403, got %d", res.StatusCode)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, handsURL, strings.NewReader(hand))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Edit-Secret", secret)
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("want status 201 Created, got %d", res.StatusCode)
+	}
+
+	res, err = http.Get(fmt.Sprintf("%s/api/v1/matches/%s", testServer.URL, created.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := apiMatch{}
+	err = json.NewDecoder(res.Body).Decode(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Score2 != 55 || len(got.Hands) != 1 || got.Hands[0].Points2 != 55 {
+		t.Errorf("want team 2 to have 55 points after one hand, got %+v", got)
+	}
+}
+
+func TestAPI_ListMatchesAndHands(t *testing.T) {
+	t.Parallel()
+	testServer := newAPITestServer(t)
+
+	for _, team := range []string{"foo", "bar"} {
+		body := fmt.Sprintf(`{"team1":%q}`, team)
+		res, err := http.Post(testServer.URL+"/api/v1/matches", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	res, err := http.Get(testServer.URL + "/api/v1/matches?team=foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := struct {
+		Matches []apiMatch `json:"matches"`
+	}{}
+	err = json.NewDecoder(res.Body).Decode(&list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Matches) != 1 || list.Matches[0].Team1 != "foo" {
+		t.Fatalf("want only the foo match listed, got %+v", list.Matches)
+	}
+
+	res, err = http.Get(fmt.Sprintf("%s/api/v1/matches/%s/hands", testServer.URL, list.Matches[0].ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hands := []any{}
+	err = json.NewDecoder(res.Body).Decode(&hands)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hands) != 0 {
+		t.Errorf("want no hands on a new match, got %d", len(hands))
+	}
+}
+
+func TestAPI_ErrorsHaveJSONBody(t *testing.T) {
+	t.Parallel()
+	testServer := newAPITestServer(t)
+
+	res, err := http.Get(testServer.URL + "/api/v1/matches/nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("want status 404 Not Found, got %d", res.StatusCode)
+	}
+	apiErr := struct {
+		Error string `json:"error"`
+	}{}
+	err = json.NewDecoder(res.Body).Decode(&apiErr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiErr.Error == "" {
+		t.Error("want error message in the body")
+	}
+
+	res, err = http.Post(testServer.URL+"/api/v1/matches", "application/json", strings.NewReader(`{"rule_set":"parchis"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("want unknown rule set rejected with 400, got %d", res.StatusCode)
+	}
+
+	res, err = http.Post(testServer.URL+"/api/v1/matches", "application/json", strings.NewReader(`{"players":{"north":"  "}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("want a blank player name rejected with 400, got %d", res.StatusCode)
+	}
+
+	res, err = http.Post(testServer.URL+"/api/v1/matches", "application/json", strings.NewReader(`{"players":{"north":"Ana","south":"ana"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("want a player in two seats rejected with 400, got %d", res.StatusCode)
+	}
+}
+
+func TestMatchPageNegotiatesJSON(t *testing.T) {
+	t.Parallel()
+	testServer := newAPITestServer(t)
+
+	res, err := http.Post(testServer.URL+"/api/v1/matches", "application/json", strings.NewReader(`{"team1":"foo"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := apiMatch{}
+	err = json.NewDecoder(res.Body).Decode(&created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/match/%s", testServer.URL, created.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Edit-Secret", res.Header.Get("X-Edit-Secret"))
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("want JSON content type, got %s", ct)
+	}
+	got := apiMatch{}
+	err = json.NewDecoder(res.Body).Decode(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != created.ID || got.Status != "in_progress" {
+		t.Errorf("want match %s in progress, got %+v", created.ID, got)
+	}
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testServer := httptest.NewServer(server.Routes())
+	defer testServer.Close()
+
+	res, err := http.Get(testServer.URL + "/api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("want status 200 OK, got %d", res.StatusCode)
+	}
+	spec := struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}{}
+	err = json.NewDecoder(res.Body).Decode(&spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("want an OpenAPI 3 document, got version %q", spec.OpenAPI)
+	}
+
+	router, ok := server.Routes().(*mux.Router)
+	if !ok {
+		t.Fatal("want routes served by a mux.Router")
+	}
+	routed := map[string]bool{}
+	err = router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		routed[path] = true
+		if _, ok := spec.Paths[path]; !ok {
+			t.Errorf("route %s has no entry in the OpenAPI document", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, operations := range spec.Paths {
+		if !routed[path] {
+			t.Errorf("OpenAPI document describes %s, which is not routed", path)
+		}
+		if len(operations) == 0 {
+			t.Errorf("want operations documented for %s", path)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package dominocount
+
+import (
+	"fmt"
+	"strings"
+)
+
+// chart geometry, in SVG user units.
+const (
+	chartWidth   = 600
+	chartHeight  = 200
+	chartMatches = 20
+)
+
+// chartBar is a bar of a chart, Y and Height already in SVG coordinates.
+type chartBar struct {
+	X, Y, Width, Height float64
+	Won                 bool
+	Title               string
+}
+
+// chart is drawn by the templates as an inline SVG, so no JavaScript is
+// needed to show it.
+type chart struct {
+	Width, Height float64
+	// Axis is the y of the reference line, like no margin or the target.
+	Axis  float64
+	Bars  []chartBar
+	Lines []chartLine
+}
+
+// chartLine is a polyline of a chart.
+type chartLine struct {
+	Name string
+	// Points are the x,y pairs of the polyline.
+	Points string
+}
+
+// ScoreChart draws the running score of both teams hand after hand, the
+// axis marks the target score.
+func (m match) ScoreChart() chart {
+	c := chart{Width: chartWidth, Height: chartHeight}
+	top := m.Target
+	if m.Score1 > top {
+		top = m.Score1
+	}
+	if m.Score2 > top {
+		top = m.Score2
+	}
+	if top == 0 {
+		top = 1
+	}
+	y := func(score int) float64 {
+		return chartHeight - float64(score)/float64(top)*chartHeight
+	}
+	c.Axis = y(m.Target)
+	step := float64(chartWidth)
+	if len(m.Hands) > 0 {
+		step = float64(chartWidth) / float64(len(m.Hands))
+	}
+	start := fmt.Sprintf("0,%.1f", y(0))
+	points1, points2 := []string{start}, []string{start}
+	score1, score2 := 0, 0
+	for i, h := range m.Hands {
+		score1, score2 = score1+h.Points1, score2+h.Points2
+		x := float64(i+1) * step
+		points1 = append(points1, fmt.Sprintf("%.1f,%.1f", x, y(score1)))
+		points2 = append(points2, fmt.Sprintf("%.1f,%.1f", x, y(score2)))
+	}
+	c.Lines = []chartLine{
+		{Name: m.Team1, Points: strings.Join(points1, " ")},
+		{Name: m.Team2, Points: strings.Join(points2, " ")},
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"dominocount"
+	"os"
+)
+
+func main() {
+	dominocount.RunRecomputeRatings(os.Stdout)
+}
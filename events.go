@@ -0,0 +1,152 @@
+package dominocount
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// hub fans out match updates to the clients watching each match. Slow
+// clients miss updates instead of blocking the writer, the next update
+// carries the whole table anyway.
+type hub struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[chan *match]struct{}
+}
+
+func newHub() *hub {
+	return &hub{subscribers: map[int64]map[chan *match]struct{}{}}
+}
+
+// Subscribe returns a channel with the updates of match id and a function to
+// stop receiving them.
+func (h *hub) Subscribe(id int64) (<-chan *match, func()) {
+	ch := make(chan *match, 1)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[id] == nil {
+		h.subscribers[id] = map[chan *match]struct{}{}
+	}
+	h.subscribers[id][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[id], ch)
+		if len(h.subscribers[id]) == 0 {
+			delete(h.subscribers, id)
+		}
+	}
+}
+
+// Publish sends m to every subscriber of its match.
+func (h *hub) Publish(m *match) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[m.Id] {
+		select {
+		case ch <- m:
+		default:
+		}
+	}
+}
+
+// liveStore publishes on the hub every match whose score changes through it.
+type liveStore struct {
+	Storage
+	hub *hub
+}
+
+func (s liveStore) AddPointsByID(id int64, score1 int, score2 int) (*match, error) {
+	return s.AddHandByID(id, NewHand(score1, score2))
+}
+
+func (s liveStore) AddHandByID(id int64, h hand) (*match, error) {
+	return s.publish(s.Storage.AddHandByID(id, h))
+}
+
+func (s liveStore) UndoLastHand(id int64) (*match, error) {
+	return s.publish(s.Storage.UndoLastHand(id))
+}
+
+func (s liveStore) UpdateHand(id int64, number int, h hand) (*match, error) {
+	return s.publish(s.Storage.UpdateHand(id, number, h))
+}
+
+func (s liveStore) AbandonMatch(id int64) (*match, error) {
+	return s.publish(s.Storage.AbandonMatch(id))
+}
+
+func (s liveStore) publish(m *match, err error) (*match, error) {
+	if err == nil {
+		s.hub.Publish(m)
+	}
+	return m, err
+}
+
+// HandleEvents streams the score table of a match as Server-Sent Events, one
+// score event each time a hand is played, undone or corrected.
+func (s server) HandleEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		id, ok := s.matchID(w, r)
+		if !ok {
+			return
+		}
+		s.streamMatch(w, r, id, matchTableTemplate)
+	}
+}
+
+// streamMatch sends the match rendered with the template as a score event
+// each time it changes, until the client goes away.
+func (s server) streamMatch(w http.ResponseWriter, r *http.Request, id int64, templateName string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	updates, unsubscribe := s.hub.Subscribe(id)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case m := <-updates:
+			err := writeEvent(w, "score", templateName, m)
+			if err != nil {
+				fmt.Fprintln(s.output, err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent renders data with the template as an SSE event. Every line of
+// the output goes in its own data field.
+func writeEvent(w http.ResponseWriter, event, templateName string, data any) error {
+	var buf bytes.Buffer
+	err := tmpl.ExecuteTemplate(&buf, templateName, data)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "event: %s\n", event)
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	_, err = fmt.Fprint(w, "\n")
+	return err
+}
@@ -0,0 +1,86 @@
+package dominocount_test
+
+import (
+	"bufio"
+	"context"
+	"dominocount"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventsStreamScoreUpdates(t *testing.T) {
+	t.Parallel()
+
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch(dominocount.MatchWithTeam1Name("foo"), dominocount.MatchWithTeam2Name("bar"))
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testServer := httptest.NewServer(server.Routes())
+	defer testServer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	url := testServer.URL + "/match/" + m.Slug
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("want event stream, got content type %s", ct)
+	}
+	events := bufio.NewScanner(res.Body)
+	events.Scan()
+	if events.Text() != ": connected" {
+		t.Fatalf("want stream to open with a comment, got %q", events.Text())
+	}
+
+	req, err = http.NewRequest(http.MethodPatch, url, strings.NewReader("team1_points=37&team2_points=0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	patch, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch.Body.Close()
+
+	event, data := "", ""
+	for events.Scan() {
+		line := events.Text()
+		if line == "" && event != "" {
+			break
+		}
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+		}
+		if d, ok := strings.CutPrefix(line, "data: "); ok {
+			data += d + "\n"
+		}
+	}
+	if event != "score" {
+		t.Fatalf("want a score event, got %q", event)
+	}
+	if !strings.Contains(data, `id="matchTable"`) || !strings.Contains(data, "37") {
+		t.Errorf("want the updated score table in the event\nGot:\n%s", data)
+	}
+}
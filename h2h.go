@@ -0,0 +1,231 @@
+package dominocount
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// recentResults is how many of the latest results a head-to-head highlights.
+const recentResults = 5
+
+// rival is a side of a head-to-head, either a team by its name or a pair of
+// players.
+type rival struct {
+	Team    string   `json:"team,omitempty"`
+	Players []string `json:"players,omitempty"`
+}
+
+// ParseRival reads a side of a head-to-head. Two player names separated by a
+// comma are a pair, anything else is the name of a team.
+func ParseRival(s string) (rival, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return rival{}, errors.New("missing team or pair of players")
+	}
+	if !strings.Contains(s, ",") {
+		return rival{Team: s}, nil
+	}
+	names := strings.Split(s, ",")
+	if len(names) != 2 {
+		return rival{}, errors.New("a pair has two players")
+	}
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+		if names[i] == "" {
+			return rival{}, errors.New("a pair has two players")
+		}
+	}
+	if strings.EqualFold(names[0], names[1]) {
+		return rival{}, errors.New("a pair has two different players")
+	}
+	return rival{Players: names}, nil
+}
+
+// Name returns the team name or both player names.
+func (r rival) Name() string {
+	if r.Team != "" {
+		return r.Team
+	}
+	return strings.Join(r.Players, " y ")
+}
+
+// String returns r the way ParseRival reads it.
+func (r rival) String() string {
+	if r.Team != "" {
+		return r.Team
+	}
+	return strings.Join(r.Players, ", ")
+}
+
+// sideIn returns the team r played for in m, empty when r did not play it.
+func (r rival) sideIn(m match) team {
+	if r.Team != "" {
+		switch {
+		case strings.EqualFold(m.Team1, r.Team):
+			return Team1
+		case strings.EqualFold(m.Team2, r.Team):
+			return Team2
+		}
+		return ""
+	}
+	var side team
+	for _, name := range r.Players {
+		var seated team
+		for _, p := range m.Players {
+			if strings.EqualFold(p.Name, name) {
+				seated = p.Seat.Team()
+			}
+		}
+		if seated == "" || (side != "" && seated != side) {
+			return ""
+		}
+		side = seated
+	}
+	return side
+}
+
+// HeadToHeadFilter lists every active match both a and b took part in, on
+// either side. newHeadToHead keeps those they played against each other.
+func HeadToHeadFilter(a, b rival) MatchFilter {
+	filter := MatchFilter{Limit: -1}
+	for _, r := range []rival{a, b} {
+		if r.Team != "" {
+			filter.Teams = append(filter.Teams, r.Team)
+		}
+		filter.Players = append(filter.Players, r.Players...)
+	}
+	return filter
+}
+
+// headToHeadMatch is a match between the rivals, Side is the team A played
+// for.
+type headToHeadMatch struct {
+	match
+	Side team `json:"side"`
+}
+
+// ScoreA returns the points of A.
+func (m headToHeadMatch) ScoreA() int {
+	return m.Score(m.Side)
+}
+
+// ScoreB returns the points of B.
+func (m headToHeadMatch) ScoreB() int {
+	if m.Side == Team1 {
+		return m.Score(Team2)
+	}
+	return m.Score(Team1)
+}
+
+// WonByA reports whether A won the match, false while it is not over.
+func (m headToHeadMatch) WonByA() bool {
+	return m.Status == StatusFinished && m.Winner() == m.Side
+}
+
+// headToHead is the record between two rivals, matches newest first.
+type headToHead struct {
+	A       rival             `json:"a"`
+	B       rival             `json:"b"`
+	Matches []headToHeadMatch `json:"matches"`
+}
+
+// newHeadToHead keeps the matches where a and b played on opposite sides.
+func newHeadToHead(a, b rival, matches []match) headToHead {
+	h := headToHead{A: a, B: b, Matches: []headToHeadMatch{}}
+	for _, m := range matches {
+		sideA, sideB := a.sideIn(m), b.sideIn(m)
+		if sideA == "" || sideB == "" || sideA == sideB {
+			continue
+		}
+		h.Matches = append(h.Matches, headToHeadMatch{match: m, Side: sideA})
+	}
+	return h
+}
+
+// finished returns the finished matches, newest first.
+func (h headToHead) finished() []headToHeadMatch {
+	finished := []headToHeadMatch{}
+	for _, m := range h.Matches {
+		if m.Status == StatusFinished {
+			finished = append(finished, m)
+		}
+	}
+	return finished
+}
+
+// Played returns how many matches between the rivals finished.
+func (h headToHead) Played() int {
+	return len(h.finished())
+}
+
+// WinsA returns the matches won by A.
+func (h headToHead) WinsA() int {
+	wins := 0
+	for _, m := range h.finished() {
+		if m.WonByA() {
+			wins++
+		}
+	}
+	return wins
+}
+
+// WinsB returns the matches won by B.
+func (h headToHead) WinsB() int {
+	return h.Played() - h.WinsA()
+}
+
+// AverageMargin returns by how many points A wins on average, negative when
+// B usually wins, 0 without finished matches.
+func (h headToHead) AverageMargin() float64 {
+	finished := h.finished()
+	if len(finished) == 0 {
+		return 0
+	}
+	margin := 0
+	for _, m := range finished {
+		margin += m.ScoreA() - m.ScoreB()
+	}
+	return float64(margin) / float64(len(finished))
+}
+
+// Recent returns the latest finished matches, newest first.
+func (h headToHead) Recent() []headToHeadMatch {
+	finished := h.finished()
+	if len(finished) > recentResults {
+		finished = finished[:recentResults]
+	}
+	return finished
+}
+
+// HandleHeadToHead renders the record between the rivals a and b in the query
+// string, or only the search form when they are missing.
+func (s server) HandleHeadToHead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		query := r.URL.Query()
+		if query.Get("a") == "" && query.Get("b") == "" {
+			render(w, r, headToHeadTemplate, headToHead{})
+			return
+		}
+		a, err := ParseRival(query.Get("a"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b, err := ParseRival(query.Get("b"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		matches, _, err := s.store.ListMatches(HeadToHeadFilter(a, b))
+		if err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		render(w, r, headToHeadTemplate, newHeadToHead(a, b, matches))
+	}
+}
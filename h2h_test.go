@@ -0,0 +1,100 @@
+package dominocount_test
+
+import (
+	"dominocount"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSQLiteStore_ListMatchesForHeadToHead(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newRatedMatch(t, &store, "Ana", "Beto", "Cara", "Dani")
+	newRatedMatch(t, &store, "Cara", "Dani", "Beto", "Ana")
+	newRatedMatch(t, &store, "Ana", "Cara", "Beto", "Dani")
+
+	a, err := dominocount.ParseRival("ana, Beto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := dominocount.ParseRival("Cara,Dani")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, next, err := store.ListMatches(dominocount.HeadToHeadFilter(a, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 3 {
+		t.Errorf("want every match both pairs sat at, got %d", len(matches))
+	}
+	if next != 0 {
+		t.Errorf("want every match in one page, got next cursor %d", next)
+	}
+
+	for _, bad := range []string{"", "Ana,", "Ana,Beto,Cara", "Ana,ana"} {
+		_, err = dominocount.ParseRival(bad)
+		if err == nil {
+			t.Errorf("want an error parsing %q", bad)
+		}
+	}
+}
+
+func TestHeadToHeadPageShowsRecord(t *testing.T) {
+	t.Parallel()
+	client, testServer, store := newAccountTestClient(t)
+	play := func(team1, team2 string, score1, score2 int) {
+		t.Helper()
+		m := dominocount.NewMatch(
+			dominocount.MatchWithTeam1Name(team1),
+			dominocount.MatchWithTeam2Name(team2),
+			dominocount.MatchWithTargetScore(100),
+		)
+		err := store.CreateMatch(&m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.AddPointsByID(m.Id, score1, score2)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	play("Los Primos", "La Esquina", 100, 20)
+	play("La Esquina", "Los Primos", 100, 60)
+	play("Los Primos", "La Esquina", 120, 0)
+	play("Los Primos", "El Colmado", 100, 0)
+
+	query := url.Values{"a": {"los primos"}, "b": {"La Esquina"}}
+	res, err := client.Get(testServer.URL + "/h2h?" + query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(body)
+	for _, want := range []string{"los primos 2 - 1 La Esquina", "3 juegos terminados", "53.3 para los primos"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in the head-to-head\nGot:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "El Colmado") {
+		t.Error("want only the matches between both teams")
+	}
+
+	res, err = client.Get(testServer.URL + "/h2h?a=Ana,&b=Cara")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("want status 400 for a pair missing a player, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,69 @@
+package dominocount
+
+import (
+	"net/http"
+)
+
+// leaderboard is the data of the ratings page.
+type leaderboard struct {
+	Players []playerRating `json:"players"`
+	Pairs   []playerRating `json:"pairs"`
+}
+
+// ratingHistory is the data of the rating page of a player.
+type ratingHistory struct {
+	Player  *player        `json:"player"`
+	Changes []ratingChange `json:"changes"`
+}
+
+// HandleRatings renders the leaderboard of players and partnerships.
+func (s server) HandleRatings() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		players, err := s.store.ListPlayerRatings()
+		if err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		pairs, err := s.store.ListPairRatings()
+		if err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		render(w, r, ratingsTemplate, leaderboard{Players: players, Pairs: pairs})
+	}
+}
+
+// HandleRatingHistory renders how the rating of a player changed match after
+// match.
+func (s server) HandleRatingHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		id, err := queryStringParseID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p, err := s.store.GetPlayerByID(id)
+		if err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		if p.ID == 0 {
+			http.Error(w, "player not found", http.StatusNotFound)
+			return
+		}
+		changes, err := s.store.ListRatingHistory(id)
+		if err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		render(w, r, ratingHistoryTemplate, ratingHistory{Player: p, Changes: changes})
+	}
+}
@@ -0,0 +1,265 @@
+package dominocount
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Default league points, the usual three for a win plus one for a zapato,
+// a win where the losers did not score.
+const (
+	DefaultWinPoints     = 3
+	DefaultLossPoints    = 0
+	DefaultShutoutPoints = 1
+)
+
+// league is a group of teams playing each other season after season. There
+// is no schedule, every finished match between two of its teams played during
+// a season counts for the standings of that season.
+type league struct {
+	ID   int64  `json:"-"`
+	Slug string `json:"id"`
+	Name string `json:"name"`
+	// WinPoints and LossPoints are the league points given for each result,
+	// ShutoutPoints are added for a win where the losers did not score.
+	WinPoints     int       `json:"win_points"`
+	LossPoints    int       `json:"loss_points"`
+	ShutoutPoints int       `json:"shutout_points"`
+	Teams         []string  `json:"teams"`
+	Seasons       []season  `json:"seasons"`
+	CreatedAt     time.Time `json:"created_at"`
+	OwnerID       int64     `json:"-"`
+	// EditSecret works like the edit secret of a match, for the league.
+	EditSecret string `json:"-"`
+}
+
+// season bounds the matches of a league by creation date. End is the last
+// day of the season, zero while the season is open.
+type season struct {
+	ID    int64     `json:"id"`
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end,omitempty"`
+}
+
+type leagueOption func(*league) error
+
+func NewLeague(opts ...leagueOption) league {
+	l := league{
+		WinPoints:     DefaultWinPoints,
+		LossPoints:    DefaultLossPoints,
+		ShutoutPoints: DefaultShutoutPoints,
+		CreatedAt:     time.Now().UTC(),
+	}
+	for _, opt := range opts {
+		//ignoring errors as current options generate no errors
+		opt(&l)
+	}
+	return l
+}
+
+func LeagueWithName(name string) leagueOption {
+	return func(l *league) error {
+		l.Name = strings.TrimSpace(name)
+		return nil
+	}
+}
+
+// LeagueWithTeams registers the teams of the league. Blank names are
+// skipped.
+func LeagueWithTeams(teams ...string) leagueOption {
+	return func(l *league) error {
+		for _, name := range teams {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				l.Teams = append(l.Teams, name)
+			}
+		}
+		return nil
+	}
+}
+
+// LeagueWithPoints sets the league points given for a win, a loss and the
+// bonus for a shutout.
+func LeagueWithPoints(win, loss, shutout int) leagueOption {
+	return func(l *league) error {
+		l.WinPoints, l.LossPoints, l.ShutoutPoints = win, loss, shutout
+		return nil
+	}
+}
+
+// LeagueWithSeason opens the league with its first season.
+func LeagueWithSeason(name string, start, end time.Time) leagueOption {
+	return func(l *league) error {
+		l.Seasons = append(l.Seasons, NewSeason(name, start, end))
+		return nil
+	}
+}
+
+func LeagueWithOwner(userID int64) leagueOption {
+	return func(l *league) error {
+		l.OwnerID = userID
+		return nil
+	}
+}
+
+// NewSeason returns a season from start to end, both days included. A zero
+// end leaves the season open.
+func NewSeason(name string, start, end time.Time) season {
+	return season{Name: strings.TrimSpace(name), Start: day(start), End: day(end)}
+}
+
+func day(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// validate checks the league can be played.
+func (l league) validate() error {
+	if l.Name == "" {
+		return errors.New("league name cannot be empty")
+	}
+	if len(l.Teams) < 2 {
+		return errors.New("a league needs at least two teams")
+	}
+	seen := map[string]bool{}
+	for _, name := range l.Teams {
+		key := strings.ToLower(name)
+		if seen[key] {
+			return errors.New("team " + name + " is registered twice")
+		}
+		seen[key] = true
+	}
+	if l.WinPoints < l.LossPoints || l.LossPoints < 0 || l.ShutoutPoints < 0 {
+		return errors.New("a win must be worth at least as much as a loss")
+	}
+	for _, s := range l.Seasons {
+		err := s.validate()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s season) validate() error {
+	if s.Name == "" {
+		return errors.New("season name cannot be empty")
+	}
+	if s.Start.IsZero() {
+		return errors.New("a season needs a start date")
+	}
+	if !s.End.IsZero() && s.End.Before(s.Start) {
+		return errors.New("a season cannot end before it starts")
+	}
+	return nil
+}
+
+// Until returns the end of the season as an exclusive bound, zero while the
+// season is open.
+func (s season) Until() time.Time {
+	if s.End.IsZero() {
+		return s.End
+	}
+	return s.End.AddDate(0, 0, 1)
+}
+
+// leagueStanding is the record of a team over a season.
+type leagueStanding struct {
+	Team          string `json:"team"`
+	Played        int    `json:"played"`
+	Won           int    `json:"won"`
+	Lost          int    `json:"lost"`
+	Shutouts      int    `json:"shutouts"`
+	PointsFor     int    `json:"points_for"`
+	PointsAgainst int    `json:"points_against"`
+	Points        int    `json:"points"`
+}
+
+// Differential returns the domino points scored minus the points conceded.
+func (s leagueStanding) Differential() int {
+	return s.PointsFor - s.PointsAgainst
+}
+
+// Standings adds up the finished matches between teams of the league, any
+// other match is left out. Teams are ranked by league points, then by
+// differential and then by points scored.
+func (l league) Standings(matches []match) []leagueStanding {
+	byTeam := map[string]*leagueStanding{}
+	standings := make([]leagueStanding, len(l.Teams))
+	for i, name := range l.Teams {
+		standings[i].Team = name
+		byTeam[strings.ToLower(name)] = &standings[i]
+	}
+	for _, m := range matches {
+		winner := m.Winner()
+		if m.Status != StatusFinished || winner == "" {
+			continue
+		}
+		team1, team2 := byTeam[strings.ToLower(m.Team1)], byTeam[strings.ToLower(m.Team2)]
+		if team1 == nil || team2 == nil || team1 == team2 {
+			continue
+		}
+		won, lost := team1, team2
+		loserScore := m.Score2
+		if winner == Team2 {
+			won, lost = team2, team1
+			loserScore = m.Score1
+		}
+		won.Won++
+		won.Points += l.WinPoints
+		if loserScore == 0 {
+			won.Shutouts++
+			won.Points += l.ShutoutPoints
+		}
+		lost.Lost++
+		lost.Points += l.LossPoints
+		team1.Played++
+		team2.Played++
+		team1.PointsFor += m.Score1
+		team1.PointsAgainst += m.Score2
+		team2.PointsFor += m.Score2
+		team2.PointsAgainst += m.Score1
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.Differential() != b.Differential() {
+			return a.Differential() > b.Differential()
+		}
+		return a.PointsFor > b.PointsFor
+	})
+	return standings
+}
+
+// standingColumns are the columns the standings can be sorted by, keyed by
+// the name used in the query string. Each sorts best first.
+var standingColumns = map[string]func(a, b leagueStanding) bool{
+	"puntos":     func(a, b leagueStanding) bool { return a.Points > b.Points },
+	"jugados":    func(a, b leagueStanding) bool { return a.Played > b.Played },
+	"ganados":    func(a, b leagueStanding) bool { return a.Won > b.Won },
+	"perdidos":   func(a, b leagueStanding) bool { return a.Lost < b.Lost },
+	"zapatos":    func(a, b leagueStanding) bool { return a.Shutouts > b.Shutouts },
+	"a_favor":    func(a, b leagueStanding) bool { return a.PointsFor > b.PointsFor },
+	"en_contra":  func(a, b leagueStanding) bool { return a.PointsAgainst < b.PointsAgainst },
+	"diferencia": func(a, b leagueStanding) bool { return a.Differential() > b.Differential() },
+	"equipo":     func(a, b leagueStanding) bool { return strings.ToLower(a.Team) < strings.ToLower(b.Team) },
+}
+
+// sortStandings orders the standings by column, keeping the ranking for
+// ties. Unknown columns leave the ranking as is.
+func sortStandings(standings []leagueStanding, column string) {
+	less, ok := standingColumns[column]
+	if !ok {
+		return
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		return less(standings[i], standings[j])
+	})
+}
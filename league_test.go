@@ -0,0 +1,182 @@
+package dominocount_test
+
+import (
+	"dominocount"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLiteStore_LeagueStandings(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now().UTC()
+	l := dominocount.NewLeague(
+		dominocount.LeagueWithName("Liga del Club"),
+		dominocount.LeagueWithTeams("Los Primos", "La Esquina", "El Colmado"),
+		dominocount.LeagueWithSeason("Invierno", today.AddDate(0, 0, -7), time.Time{}),
+	)
+	err = store.CreateLeague(&l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	play := func(team1, team2 string, score1, score2 int) int64 {
+		t.Helper()
+		m := dominocount.NewMatch(
+			dominocount.MatchWithTeam1Name(team1),
+			dominocount.MatchWithTeam2Name(team2),
+			dominocount.MatchWithTargetScore(100),
+		)
+		err := store.CreateMatch(&m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.AddPointsByID(m.Id, score1, score2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m.Id
+	}
+	play("Los Primos", "La Esquina", 100, 0)
+	play("la esquina", "El Colmado", 120, 80)
+	play("El Colmado", "Los Primos", 100, 90)
+	play("Los Primos", "Visitantes", 100, 0)
+	archived := play("El Colmado", "La Esquina", 100, 0)
+	err = store.DeleteMatch(archived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := dominocount.NewMatch(dominocount.MatchWithTeam1Name("Los Primos"), dominocount.MatchWithTeam2Name("El Colmado"))
+	err = store.CreateMatch(&open)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	standings, err := store.LeagueStandings(l.ID, l.Seasons[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		team                             string
+		played, won, shutouts, diff, pts int
+	}{
+		{"Los Primos", 2, 1, 1, 90, 4},
+		{"El Colmado", 2, 1, 0, -30, 3},
+		{"La Esquina", 2, 1, 0, -60, 3},
+	}
+	if len(standings) != len(want) {
+		t.Fatalf("want %d teams, got %+v", len(want), standings)
+	}
+	for i, w := range want {
+		got := standings[i]
+		if got.Team != w.team || got.Played != w.played || got.Won != w.won || got.Shutouts != w.shutouts || got.Differential() != w.diff || got.Points != w.pts {
+			t.Errorf("want %+v at %d, got %+v", w, i+1, got)
+		}
+	}
+
+	next := dominocount.NewSeason("Verano", today.AddDate(0, 0, 1), time.Time{})
+	err = store.AddSeason(l.ID, &next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	standings, err = store.LeagueStandings(l.ID, next.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range standings {
+		if s.Played != 0 {
+			t.Errorf("want no matches in a season that has not started, got %+v", s)
+		}
+	}
+
+	bad := dominocount.NewSeason("Al revés", today, today.AddDate(0, 0, -1))
+	err = store.AddSeason(l.ID, &bad)
+	if err == nil {
+		t.Error("want an error adding a season that ends before it starts")
+	}
+}
+
+func TestLeaguePageSortsStandingsAndGuardsSeasons(t *testing.T) {
+	t.Parallel()
+	client, testServer, store := newAccountTestClient(t)
+
+	res, err := client.PostForm(testServer.URL+"/leagues", map[string][]string{
+		"name":         {"Liga del Club"},
+		"teams":        {"Los Primos\r\nLa Esquina\r\n"},
+		"season_name":  {"Invierno"},
+		"season_start": {time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("want the league page after creating it, got %d", res.StatusCode)
+	}
+	slug := strings.TrimPrefix(res.Request.URL.Path, "/leagues/")
+
+	m := dominocount.NewMatch(dominocount.MatchWithTeam1Name("La Esquina"), dominocount.MatchWithTeam2Name("Los Primos"))
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 0, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = client.Get(testServer.URL + "/leagues/" + slug + "?orden=perdidos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(body)
+	primos, esquina := strings.Index(got, "<td class=\"border px-4 py-2\">Los Primos"), strings.Index(got, "<td class=\"border px-4 py-2\">La Esquina")
+	if primos < 0 || esquina < 0 || primos > esquina {
+		t.Errorf("want Los Primos above La Esquina sorted by losses\nGot:\n%s", got)
+	}
+	if !strings.Contains(got, "Nueva temporada") {
+		t.Error("want the creator to be able to open a new season")
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	visitor := &http.Client{Jar: jar}
+	res, err = visitor.PostForm(testServer.URL+"/leagues/"+slug+"/seasons", map[string][]string{
+		"season_name":  {"Verano"},
+		"season_start": {"2030-01-01"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("want status 403 opening a season without the edit secret, got %d", res.StatusCode)
+	}
+
+	res, err = client.PostForm(testServer.URL+"/leagues/"+slug+"/seasons", map[string][]string{
+		"season_name":  {"Verano"},
+		"season_start": {"2030-01-01"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || !strings.Contains(string(body), "desde 01/01/2030") {
+		t.Errorf("want the new season shown after opening it, got %d\n%s", res.StatusCode, body)
+	}
+}
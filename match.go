@@ -1,24 +1,133 @@
 package dominocount
 
+import "time"
+
 type match struct {
-	Score1, Score2 int
-	Team1, Team2   string
-	Id             int64
+	Score1  int      `json:"score1"`
+	Score2  int      `json:"score2"`
+	Team1   string   `json:"team1"`
+	Team2   string   `json:"team2"`
+	Target  int      `json:"target_score"`
+	Rules   RuleSet  `json:"rule_set"`
+	Players []player `json:"players"`
+	Id      int64    `json:"-"`
+	// Slug identifies the match in URLs, random so matches cannot be
+	// enumerated.
+	Slug      string      `json:"id"`
+	Hands     []hand      `json:"hands"`
+	Status    matchStatus `json:"status"`
+	CreatedAt time.Time   `json:"created_at"`
+	// FinishedAt and ArchivedAt are left out of JSON while unset, see
+	// MarshalJSON.
+	FinishedAt time.Time `json:"-"`
+	// ArchivedAt is set while the match is deleted, archived matches are kept
+	// so they can be restored.
+	ArchivedAt time.Time `json:"-"`
+	// WatchToken opens the read-only spectator view of the match.
+	WatchToken string `json:"-"`
+	// EditSecret is only known right after the match is created, or when the
+	// request editing the match has proved it.
+	EditSecret string `json:"-"`
+	// OwnerID is the user who created the match, 0 for anonymous matches.
+	OwnerID int64 `json:"-"`
+}
+
+// hand is a single round of a match, the points each team scored on it and
+// when it was played. Number is the 1-based position of the hand in its match.
+type hand struct {
+	MatchID  int64          `json:"-"`
+	Number   int            `json:"number"`
+	Points1  int            `json:"team1_points"`
+	Points2  int            `json:"team2_points"`
+	PlayedAt time.Time      `json:"played_at"`
+	Bonuses  []awardedBonus `json:"bonuses"`
+	// Tranque is set when the hand ended in a blocked game.
+	Tranque bool `json:"tranque"`
+	// Domino is the seat of the player who won the hand, empty when unknown.
+	Domino seat `json:"domino,omitempty"`
+}
+
+// awardedBonus records a bonus a team earned on a hand and the points it was
+// worth under the rules of the match. The points are already included in the
+// hand totals.
+type awardedBonus struct {
+	Team   team  `json:"team"`
+	Kind   bonus `json:"kind"`
+	Points int   `json:"points"`
+}
+
+// NewHand returns a hand to be played where each team made the given raw
+// points.
+func NewHand(points1, points2 int) hand {
+	return hand{Points1: points1, Points2: points2, Bonuses: []awardedBonus{}}
+}
+
+// WithBonus returns a copy of the hand where t claims bonus b.
+func (h hand) WithBonus(t team, b bonus) hand {
+	bonuses := append([]awardedBonus{}, h.Bonuses...)
+	h.Bonuses = append(bonuses, awardedBonus{Team: t, Kind: b})
+	return h
+}
+
+// WonBy returns a copy of the hand won by the player on seat s.
+func (h hand) WonBy(s seat) hand {
+	h.Domino = s
+	return h
+}
+
+// BonusesFor returns the bonuses awarded to t on the hand.
+func (h hand) BonusesFor(t team) []awardedBonus {
+	bonuses := []awardedBonus{}
+	for _, b := range h.Bonuses {
+		if b.Team == t {
+			bonuses = append(bonuses, b)
+		}
+	}
+	return bonuses
+}
+
+// HasBonus reports whether t was awarded bonus b on the hand.
+func (h hand) HasBonus(t team, b bonus) bool {
+	for _, awarded := range h.Bonuses {
+		if awarded.Team == t && awarded.Kind == b {
+			return true
+		}
+	}
+	return false
 }
+
+// RawPoints returns the points t made on the hand without its bonuses.
+func (h hand) RawPoints(t team) int {
+	points := h.Points2
+	if t == Team1 {
+		points = h.Points1
+	}
+	for _, b := range h.BonusesFor(t) {
+		points -= b.Points
+	}
+	return points
+}
+
 type matchOption func(*match) error
 
 func NewMatch(opts ...matchOption) match {
 	m := match{
-		Team1:  string(Team1),
-		Team2:  string(Team2),
-		Score1: 0,
-		Score2: 0,
+		Team1:     string(Team1),
+		Team2:     string(Team2),
+		Score1:    0,
+		Score2:    0,
+		Rules:     DominicanRules,
+		Status:    StatusInProgress,
+		CreatedAt: time.Now().UTC(),
 	}
 
 	for _, opt := range opts {
-		//ignoring errors as current name option generates no errors
+		//ignoring errors as current options generate no errors
 		opt(&m)
 	}
+	if m.Target == 0 {
+		m.Target = m.Rules.TargetScore()
+	}
 	return m
 }
 
@@ -40,13 +149,88 @@ func MatchWithTeam2Name(name string) matchOption {
 	}
 }
 
+// MatchWithTargetScore sets the points a team needs to win the match. Values
+// below 1 keep the target of the match rule set.
+func MatchWithTargetScore(target int) matchOption {
+	return func(m *match) error {
+		if target > 0 {
+			m.Target = target
+		}
+		return nil
+	}
+}
+
+// MatchWithRuleSet sets the variant the match is scored with. A nil rule set
+// keeps the default dominican rules.
+func MatchWithRuleSet(r RuleSet) matchOption {
+	return func(m *match) error {
+		if r != nil {
+			m.Rules = r
+		}
+		return nil
+	}
+}
+
+// MatchWithOwner makes the user the owner of the match.
+func MatchWithOwner(userID int64) matchOption {
+	return func(m *match) error {
+		m.OwnerID = userID
+		return nil
+	}
+}
+
 func (m *match) AddPoints(t team, points int) {
-	if m.GameOver() {
+	if m.GameOver() || m.Status == StatusAbandoned {
 		return
 	}
 	if points < 0 {
 		return
 	}
+	m.score(t, m.Rules.HandPoints(points))
+	m.updateStatus()
+}
+
+// PlayHand scores a hand entered as the raw points of each team plus the
+// bonuses claimed. Points go through the rule set and bonuses are valued by
+// it, bonuses the variant does not play are dropped. It returns the hand as it
+// should be recorded, with zero points if the match is already closed.
+func (m *match) PlayHand(h hand) hand {
+	if m.GameOver() || m.Status == StatusAbandoned {
+		return hand{MatchID: m.Id, Bonuses: []awardedBonus{}, Tranque: h.Tranque, Domino: h.Domino}
+	}
+	played := m.scoreHand(h)
+	m.score(Team1, played.Points1)
+	m.score(Team2, played.Points2)
+	m.updateStatus()
+	return played
+}
+
+// scoreHand returns h as the rules of the match score it, the raw points
+// adjusted and the bonuses the rules reward added. The totals are left alone.
+func (m match) scoreHand(h hand) hand {
+	played := hand{MatchID: m.Id, Bonuses: []awardedBonus{}, Tranque: h.Tranque, Domino: h.Domino}
+	if h.Points1 > 0 {
+		played.Points1 = m.Rules.HandPoints(h.Points1)
+	}
+	if h.Points2 > 0 {
+		played.Points2 = m.Rules.HandPoints(h.Points2)
+	}
+	for _, b := range h.Bonuses {
+		b.Points = m.Rules.Bonus(b.Kind)
+		if b.Points == 0 {
+			continue
+		}
+		if b.Team == Team1 {
+			played.Points1 += b.Points
+		} else {
+			played.Points2 += b.Points
+		}
+		played.Bonuses = append(played.Bonuses, b)
+	}
+	return played
+}
+
+func (m *match) score(t team, points int) {
 	if t == Team1 {
 		m.Score1 += points
 		return
@@ -54,6 +238,44 @@ func (m *match) AddPoints(t team, points int) {
 	m.Score2 += points
 }
 
+// Bonuses returns the bonuses played under the rule set of the match.
+func (m match) Bonuses() []bonus {
+	bonuses := []bonus{}
+	for _, b := range allBonuses {
+		if m.Rules.Bonus(b) > 0 {
+			bonuses = append(bonuses, b)
+		}
+	}
+	return bonuses
+}
+
+// Abandon closes a match that is still in progress without a winner.
+func (m *match) Abandon() {
+	if m.Status != StatusInProgress {
+		return
+	}
+	m.Status = StatusAbandoned
+	m.FinishedAt = time.Now().UTC()
+}
+
+// updateStatus finishes the match once a team reaches the target and reopens
+// it when a correction takes both teams below it again. Abandoned matches are
+// left alone.
+func (m *match) updateStatus() {
+	if m.Status == StatusAbandoned {
+		return
+	}
+	if m.GameOver() {
+		if m.Status != StatusFinished {
+			m.Status = StatusFinished
+			m.FinishedAt = time.Now().UTC()
+		}
+		return
+	}
+	m.Status = StatusInProgress
+	m.FinishedAt = time.Time{}
+}
+
 func (m match) Score(t team) int {
 	if t == Team1 {
 		return m.Score1
@@ -62,12 +284,94 @@ func (m match) Score(t team) int {
 }
 
 func (m match) GameOver() bool {
-	if m.Score1 >= 200 || m.Score2 >= 200 {
+	if m.Score1 >= m.Target || m.Score2 >= m.Target {
 		return true
 	}
 	return false
 }
 
+// Winner returns the team that reached the target score or an empty team
+// while the game is not over.
+func (m match) Winner() team {
+	if !m.GameOver() {
+		return ""
+	}
+	if m.Score1 >= m.Score2 {
+		return Team1
+	}
+	return Team2
+}
+
+// WinnerName returns the name of the winning team, empty if there is none.
+func (m match) WinnerName() string {
+	switch m.Winner() {
+	case Team1:
+		return m.Team1
+	case Team2:
+		return m.Team2
+	}
+	return ""
+}
+
+// Tiles returns the domino set the match is played with.
+func (m match) Tiles() []tile {
+	return Tiles(m.Rules.MaxPip())
+}
+
+// PlaysTranque reports whether blocked games are settled under the match rules.
+func (m match) PlaysTranque() bool {
+	return m.Rules.Tranque() != TranqueNotPlayed
+}
+
+// TieGoesToStarter reports whether a tied tranque is won by the team that
+// opened the hand.
+func (m match) TieGoesToStarter() bool {
+	return m.Rules.Tranque() == TranqueTieStarter
+}
+
+// Archived reports whether the match was deleted.
+func (m match) Archived() bool {
+	return !m.ArchivedAt.IsZero()
+}
+
+// InProgress reports whether the match still accepts points.
+func (m match) InProgress() bool {
+	return m.Status == StatusInProgress
+}
+
+// handIndex returns the position of hand number in m.Hands or -1 if the match
+// has no such hand.
+func (m match) handIndex(number int) int {
+	for i, h := range m.Hands {
+		if h.Number == number {
+			return i
+		}
+	}
+	return -1
+}
+
+// DefaultTargetScore is the points needed to win a dominican match.
+const DefaultTargetScore = 200
+
+type matchStatus string
+
+const (
+	StatusInProgress matchStatus = "in_progress"
+	StatusFinished   matchStatus = "finished"
+	StatusAbandoned  matchStatus = "abandoned"
+)
+
+// Label is the status shown to players.
+func (s matchStatus) Label() string {
+	switch s {
+	case StatusFinished:
+		return "terminado"
+	case StatusAbandoned:
+		return "abandonado"
+	}
+	return "en juego"
+}
+
 type team string
 
 const (
@@ -80,3 +80,84 @@ func TestCannotScoreNegativeNumbers(t *testing.T) {
 		t.Errorf("want team 2 score to be %d, got %d", want, got)
 	}
 }
+
+func TestGameOverAtConfiguredTargetScore(t *testing.T) {
+	t.Parallel()
+	m := dominocount.NewMatch(dominocount.MatchWithTargetScore(100))
+	m.AddPoints(dominocount.Team1, 99)
+	if m.GameOver() {
+		t.Errorf("game shouldn't be over below 100 points")
+	}
+
+	m.AddPoints(dominocount.Team1, 1)
+	if !m.GameOver() {
+		t.Errorf("want game over at 100 points")
+	}
+}
+
+func TestMatchWithTargetScoreIgnoresNonPositive(t *testing.T) {
+	t.Parallel()
+	m := dominocount.NewMatch(dominocount.MatchWithTargetScore(0))
+	if m.Target != dominocount.DefaultTargetScore {
+		t.Errorf("want default target %d, got %d", dominocount.DefaultTargetScore, m.Target)
+	}
+}
+
+func TestWinnerIsTeamThatReachedTarget(t *testing.T) {
+	t.Parallel()
+	m := dominocount.NewMatch()
+	if m.Winner() != "" {
+		t.Errorf("want no winner before game over, got %s", m.Winner())
+	}
+
+	m.AddPoints(dominocount.Team2, 210)
+	if m.Winner() != dominocount.Team2 {
+		t.Errorf("want winner to be %s, got %s", dominocount.Team2, m.Winner())
+	}
+	if m.Status != dominocount.StatusFinished {
+		t.Errorf("want status %s, got %s", dominocount.StatusFinished, m.Status)
+	}
+	if m.FinishedAt.IsZero() {
+		t.Error("want finished timestamp to be set")
+	}
+}
+
+func TestCannotScoreAfterAbandon(t *testing.T) {
+	t.Parallel()
+	m := dominocount.NewMatch()
+	m.Abandon()
+	m.AddPoints(dominocount.Team1, 10)
+
+	if m.Score1 != 0 {
+		t.Errorf("score shouldn't change after abandoning, got %d", m.Score1)
+	}
+	if m.Status != dominocount.StatusAbandoned {
+		t.Errorf("want status %s, got %s", dominocount.StatusAbandoned, m.Status)
+	}
+}
+
+func TestPlayHandAddsBonusesFromRuleSet(t *testing.T) {
+	t.Parallel()
+	m := dominocount.NewMatch()
+	played := m.PlayHand(dominocount.NewHand(30, 0).WithBonus(dominocount.Team1, dominocount.BonusCapicua))
+
+	if played.Points1 != 55 {
+		t.Errorf("want hand to score 30 plus a 25 capicúa bonus, got %d", played.Points1)
+	}
+	if m.Score1 != 55 {
+		t.Errorf("want team 1 score 55, got %d", m.Score1)
+	}
+	if len(played.Bonuses) != 1 || played.Bonuses[0].Points != 25 {
+		t.Errorf("want capicúa bonus worth 25 recorded, got %+v", played.Bonuses)
+	}
+}
+
+func TestPlayHandDropsBonusesNotInRuleSet(t *testing.T) {
+	t.Parallel()
+	m := dominocount.NewMatch(dominocount.MatchWithRuleSet(dominocount.CubanRules))
+	played := m.PlayHand(dominocount.NewHand(30, 0).WithBonus(dominocount.Team1, dominocount.BonusCapicua))
+
+	if played.Points1 != 30 || len(played.Bonuses) != 0 {
+		t.Errorf("want cuban hand without bonuses scoring 30, got %d with %+v", played.Points1, played.Bonuses)
+	}
+}
@@ -0,0 +1,83 @@
+package dominocount
+
+import "errors"
+
+// seat is the position of a player at the table. Partners sit across from
+// each other, north and south play for the first team and east and west for
+// the second.
+type seat string
+
+const (
+	SeatNorth seat = "north"
+	SeatEast  seat = "east"
+	SeatSouth seat = "south"
+	SeatWest  seat = "west"
+)
+
+var allSeats = []seat{SeatNorth, SeatSouth, SeatEast, SeatWest}
+
+// parseSeat returns the seat named s.
+func parseSeat(s string) (seat, error) {
+	for _, st := range allSeats {
+		if string(st) == s {
+			return st, nil
+		}
+	}
+	return "", errors.New("unknown seat " + s)
+}
+
+// Team returns the partnership playing from the seat.
+func (s seat) Team() team {
+	if s == SeatNorth || s == SeatSouth {
+		return Team1
+	}
+	return Team2
+}
+
+// player is a person registered to play. Seat is only set for the players of
+// a match.
+type player struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Seat seat   `json:"seat,omitempty"`
+}
+
+// MatchWithPlayer seats a player at the match. Empty names are ignored and a
+// player already on the seat is replaced.
+func MatchWithPlayer(s seat, name string) matchOption {
+	return func(m *match) error {
+		if name == "" {
+			return nil
+		}
+		for i, p := range m.Players {
+			if p.Seat == s {
+				m.Players[i].Name = name
+				return nil
+			}
+		}
+		m.Players = append(m.Players, player{Name: name, Seat: s})
+		return nil
+	}
+}
+
+// PlayersOf returns the players of team t.
+func (m match) PlayersOf(t team) []player {
+	players := []player{}
+	for _, p := range m.Players {
+		if p.Seat.Team() == t {
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+// PlayerName returns the name of the player on seat s, empty if the seat is
+// not taken.
+func (m match) PlayerName(s seat) string {
+	for _, p := range m.Players {
+		if p.Seat == s {
+			return p.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,36 @@
+package dominocount_test
+
+import (
+	"dominocount"
+	"testing"
+)
+
+func TestSeatsArePartneredAcrossTheTable(t *testing.T) {
+	t.Parallel()
+	if dominocount.SeatNorth.Team() != dominocount.Team1 || dominocount.SeatSouth.Team() != dominocount.Team1 {
+		t.Errorf("want north and south to play for %s", dominocount.Team1)
+	}
+	if dominocount.SeatEast.Team() != dominocount.Team2 || dominocount.SeatWest.Team() != dominocount.Team2 {
+		t.Errorf("want east and west to play for %s", dominocount.Team2)
+	}
+}
+
+func TestMatchWithPlayerSeatsPlayers(t *testing.T) {
+	t.Parallel()
+	m := dominocount.NewMatch(
+		dominocount.MatchWithPlayer(dominocount.SeatNorth, "Ana"),
+		dominocount.MatchWithPlayer(dominocount.SeatEast, "Luis"),
+		dominocount.MatchWithPlayer(dominocount.SeatSouth, "Juan"),
+		dominocount.MatchWithPlayer(dominocount.SeatWest, ""),
+	)
+	if len(m.Players) != 3 {
+		t.Fatalf("want empty names to be ignored and 3 players seated, got %d", len(m.Players))
+	}
+	team1 := m.PlayersOf(dominocount.Team1)
+	if len(team1) != 2 || team1[0].Name != "Ana" || team1[1].Name != "Juan" {
+		t.Errorf("want Ana and Juan on team 1, got %+v", team1)
+	}
+	if m.PlayerName(dominocount.SeatEast) != "Luis" {
+		t.Errorf("want Luis sitting east, got %q", m.PlayerName(dominocount.SeatEast))
+	}
+}
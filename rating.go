@@ -0,0 +1,78 @@
+package dominocount
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// InitialRating is the Elo rating of players and partnerships before their
+// first finished match.
+const InitialRating = 1500.0
+
+// ratingK is how many rating points a single match can move at most.
+const ratingK = 32.0
+
+// playerRating is the Elo rating of a player, or of a partnership when
+// Partner is set.
+type playerRating struct {
+	Rank    int       `json:"rank"`
+	Player  player    `json:"player"`
+	Partner *player   `json:"partner,omitempty"`
+	Rating  float64   `json:"rating"`
+	Matches int       `json:"matches"`
+	Updated time.Time `json:"updated_at"`
+}
+
+// ratingChange is the rating a player had before and after a match.
+type ratingChange struct {
+	MatchSlug string    `json:"match"`
+	Team1     string    `json:"team1"`
+	Team2     string    `json:"team2"`
+	Before    float64   `json:"before"`
+	After     float64   `json:"after"`
+	PlayedAt  time.Time `json:"played_at"`
+}
+
+// Delta returns the points won or lost in the match.
+func (c ratingChange) Delta() float64 {
+	return c.After - c.Before
+}
+
+// expectedScore is the chance, by Elo, of a side rated rating beating a side
+// rated opponent.
+func expectedScore(rating, opponent float64) float64 {
+	return 1 / (1 + math.Pow(10, (opponent-rating)/400))
+}
+
+// eloDelta returns the rating change of a side rated rating that played a
+// side rated opponent, score is 1 for a win and 0 for a loss.
+func eloDelta(rating, opponent, score float64) float64 {
+	return ratingK * (score - expectedScore(rating, opponent))
+}
+
+// average returns the mean of the ratings.
+func average(ratings []float64) float64 {
+	sum := 0.0
+	for _, r := range ratings {
+		sum += r
+	}
+	return sum / float64(len(ratings))
+}
+
+// RunRecomputeRatings rebuilds every rating from the finished matches of the
+// store used by RunServer, for when the rating rules change.
+func RunRecomputeRatings(output io.Writer) {
+	store, err := OpenSQLiteStore(storePath(output))
+	if err != nil {
+		fmt.Fprintln(output, err)
+		return
+	}
+	rated, err := store.RecomputeRatings()
+	if err != nil {
+		fmt.Fprintln(output, err)
+		return
+	}
+	fmt.Fprintf(output, "ratings recomputed from %d matches\n", rated)
+}
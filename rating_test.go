@@ -0,0 +1,200 @@
+package dominocount_test
+
+import (
+	"dominocount"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newRatedMatch(t *testing.T, store dominocount.Storage, north, south, east, west string) int64 {
+	t.Helper()
+	m := dominocount.NewMatch(
+		dominocount.MatchWithPlayer(dominocount.SeatNorth, north),
+		dominocount.MatchWithPlayer(dominocount.SeatSouth, south),
+		dominocount.MatchWithPlayer(dominocount.SeatEast, east),
+		dominocount.MatchWithPlayer(dominocount.SeatWest, west),
+	)
+	err := store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.Id
+}
+
+func TestSQLiteStore_RatingsFollowFinishedMatches(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := newRatedMatch(t, &store, "Ana", "Beto", "Cara", "Dani")
+	_, err = store.AddPointsByID(first, 150, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ratings, err := store.ListPlayerRatings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ratings) != 0 {
+		t.Fatalf("want no ratings before a match finishes, got %+v", ratings)
+	}
+
+	_, err = store.AddPointsByID(first, 60, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ratings, err = store.ListPlayerRatings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{"Ana": 1516, "Beto": 1516, "Cara": 1484, "Dani": 1484}
+	if len(ratings) != len(want) {
+		t.Fatalf("want %d rated players, got %+v", len(want), ratings)
+	}
+	for _, r := range ratings {
+		if math.Abs(r.Rating-want[r.Player.Name]) > 0.001 || r.Matches != 1 {
+			t.Errorf("want %s at %.0f after one match, got %+v", r.Player.Name, want[r.Player.Name], r)
+		}
+	}
+	pairs, err := store.ListPairRatings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 2 || pairs[0].Partner == nil || math.Abs(pairs[0].Rating-1516) > 0.001 {
+		t.Fatalf("want Ana and Beto leading the partnerships, got %+v", pairs)
+	}
+
+	second := newRatedMatch(t, &store, "Cara", "Ana", "Beto", "Dani")
+	_, err = store.AddPointsByID(second, 0, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	incremental, err := store.ListPlayerRatings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rated, err := store.RecomputeRatings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rated != 2 {
+		t.Errorf("want 2 matches rated, got %d", rated)
+	}
+	recomputed, err := store.ListPlayerRatings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range incremental {
+		if incremental[i].Player != recomputed[i].Player || math.Abs(incremental[i].Rating-recomputed[i].Rating) > 0.001 {
+			t.Errorf("want the same ratings after a recompute, got %+v and %+v", incremental[i], recomputed[i])
+		}
+	}
+
+	history, err := store.ListRatingHistory(ratings[0].Player.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("want a change per match in the history, got %+v", history)
+	}
+
+	_, err = store.UndoLastHand(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ratings, err = store.ListPlayerRatings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range ratings {
+		if r.Matches != 1 {
+			t.Errorf("want only the second match rated after reopening the first, got %+v", r)
+		}
+	}
+}
+
+func TestSQLiteStore_RatingsLeaveOutArchivedMatches(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := newRatedMatch(t, &store, "Ana", "Beto", "Cara", "Dani")
+	_, err = store.AddPointsByID(id, 200, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.DeleteMatch(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ratings, err := store.ListPlayerRatings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ratings) != 0 {
+		t.Errorf("want no ratings once the only match is archived, got %+v", ratings)
+	}
+	rated, err := store.RecomputeRatings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rated != 0 {
+		t.Errorf("want the archived match left out of a recompute, got %d rated", rated)
+	}
+
+	err = store.RestoreMatch(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ratings, err = store.ListPlayerRatings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ratings) != 4 || math.Abs(ratings[0].Rating-1516) > 0.001 {
+		t.Errorf("want the ratings back after restoring the match, got %+v", ratings)
+	}
+}
+
+func TestRatingsPageListsLeaderboard(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := newRatedMatch(t, &store, "Ana", "Beto", "Cara", "Dani")
+	_, err = store.AddPointsByID(id, 200, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ratings", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want status 200 OK, got %d", rec.Code)
+	}
+	got := rec.Body.String()
+	for _, want := range []string{"Ana", "1516", "Ana y Beto", "1484"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q on the leaderboard\nGot:\n%s", want, got)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	server.Routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ratings/players/999", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("want status 404 for an unknown player, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,218 @@
+package dominocount
+
+import (
+	"errors"
+	"math"
+)
+
+// RuleSet describes how a domino variant is scored: the points needed to win,
+// the tiles in play, the bonuses awarded and how a blocked game is settled.
+type RuleSet interface {
+	// Name is the stable identifier persisted with a match.
+	Name() string
+	// String is the name shown to players.
+	String() string
+	TargetScore() int
+	// MaxPip is the highest number of pips on a tile half, 6 for a
+	// double-six set and 9 for a double-nine set.
+	MaxPip() int
+	// HandPoints converts the pips counted at the end of a hand into the
+	// points awarded for it.
+	HandPoints(pips int) int
+	// Bonus returns the points awarded for b, 0 when the variant does not
+	// play it.
+	Bonus(b bonus) int
+	Tranque() tranqueRule
+}
+
+type bonus string
+
+const (
+	BonusCapicua     bonus = "capicua"
+	BonusPaseCorrido bonus = "pase_corrido"
+	BonusSalida      bonus = "salida"
+)
+
+var allBonuses = []bonus{BonusCapicua, BonusPaseCorrido, BonusSalida}
+
+// parseBonus returns the bonus named s.
+func parseBonus(s string) (bonus, error) {
+	for _, b := range allBonuses {
+		if string(b) == s {
+			return b, nil
+		}
+	}
+	return "", errors.New("unknown bonus " + s)
+}
+
+// Label is the name of the bonus shown to players.
+func (b bonus) Label() string {
+	switch b {
+	case BonusCapicua:
+		return "capicúa"
+	case BonusPaseCorrido:
+		return "pase corrido"
+	case BonusSalida:
+		return "salida"
+	}
+	return string(b)
+}
+
+// tranqueRule tells whether a variant plays blocked games (tranques) and how
+// a tie between both teams is resolved.
+type tranqueRule int
+
+const (
+	// TranqueNotPlayed variants have no tranque resolution.
+	TranqueNotPlayed tranqueRule = iota
+	// TranqueTieVoid awards no points when both teams are tied.
+	TranqueTieVoid
+	// TranqueTieStarter awards the hand to the team that opened it.
+	TranqueTieStarter
+)
+
+var (
+	DominicanRules RuleSet = rules{
+		name:    "dominicano",
+		title:   "Dominicano",
+		target:  DefaultTargetScore,
+		maxPip:  6,
+		bonuses: map[bonus]int{BonusCapicua: 25, BonusPaseCorrido: 25, BonusSalida: 25},
+		tranque: TranqueTieVoid,
+	}
+	PuertoRicanRules RuleSet = rules{
+		name:    "puertorriqueno",
+		title:   "Puertorriqueño",
+		target:  500,
+		maxPip:  6,
+		bonuses: map[bonus]int{BonusCapicua: 100},
+		tranque: TranqueTieStarter,
+	}
+	CubanRules RuleSet = rules{
+		name:    "cubano",
+		title:   "Cubano doble nueve",
+		target:  100,
+		maxPip:  9,
+		tranque: TranqueTieVoid,
+	}
+	To500Rules RuleSet = rules{
+		name:    "a500",
+		title:   "A 500",
+		target:  500,
+		maxPip:  6,
+		roundTo: 5,
+		tranque: TranqueNotPlayed,
+	}
+)
+
+// RuleSets returns the built-in rule sets, the default one first.
+func RuleSets() []RuleSet {
+	return []RuleSet{DominicanRules, PuertoRicanRules, CubanRules, To500Rules}
+}
+
+// RuleSetByName returns the built-in rule set with the given name.
+func RuleSetByName(name string) (RuleSet, error) {
+	for _, r := range RuleSets() {
+		if r.Name() == name {
+			return r, nil
+		}
+	}
+	return nil, errors.New("unknown rule set " + name)
+}
+
+// TranqueHand settles a blocked game from the pips left in each player's
+// hand, pips1 for the players of the first team and pips2 for the second. The
+// team with the lowest total takes the pips of everyone. Ties are resolved
+// by the rule set, starter is the team that opened the hand and is only
+// needed when ties go to it.
+func (m match) TranqueHand(pips1, pips2 []int, starter team) (hand, error) {
+	if m.Rules.Tranque() == TranqueNotPlayed {
+		return hand{}, errors.New(m.Rules.String() + " rules have no tranque")
+	}
+	total1, err := sumPips(pips1)
+	if err != nil {
+		return hand{}, err
+	}
+	total2, err := sumPips(pips2)
+	if err != nil {
+		return hand{}, err
+	}
+
+	winner := Team1
+	switch {
+	case total2 < total1:
+		winner = Team2
+	case total1 == total2 && m.Rules.Tranque() == TranqueTieVoid:
+		h := NewHand(0, 0)
+		h.Tranque = true
+		return h, nil
+	case total1 == total2:
+		if starter != Team1 && starter != Team2 {
+			return hand{}, errors.New("tied tranque needs the team that opened the hand")
+		}
+		winner = starter
+	}
+
+	h := NewHand(total1+total2, 0)
+	if winner == Team2 {
+		h = NewHand(0, total1+total2)
+	}
+	h.Tranque = true
+	return h, nil
+}
+
+func sumPips(pips []int) (int, error) {
+	if len(pips) != 2 {
+		return 0, errors.New("want the pips of both players of a team")
+	}
+	total := 0
+	for _, p := range pips {
+		if p < 0 {
+			return 0, errors.New("pips cannot be negative")
+		}
+		total += p
+	}
+	return total, nil
+}
+
+// rules is a table driven RuleSet used by the built-in variants.
+type rules struct {
+	name, title string
+	target      int
+	maxPip      int
+	// roundTo rounds hand points to the nearest multiple, 0 keeps them as is.
+	roundTo int
+	bonuses map[bonus]int
+	tranque tranqueRule
+}
+
+func (r rules) Name() string {
+	return r.name
+}
+
+func (r rules) String() string {
+	return r.title
+}
+
+func (r rules) TargetScore() int {
+	return r.target
+}
+
+func (r rules) MaxPip() int {
+	return r.maxPip
+}
+
+func (r rules) HandPoints(pips int) int {
+	if r.roundTo <= 0 {
+		return pips
+	}
+	return int(math.Round(float64(pips)/float64(r.roundTo))) * r.roundTo
+}
+
+func (r rules) Bonus(b bonus) int {
+	return r.bonuses[b]
+}
+
+func (r rules) Tranque() tranqueRule {
+	return r.tranque
+}
@@ -0,0 +1,97 @@
+package dominocount_test
+
+import (
+	"dominocount"
+	"testing"
+)
+
+func TestRuleSetByNameFindsBuiltInRuleSets(t *testing.T) {
+	t.Parallel()
+	for _, want := range dominocount.RuleSets() {
+		got, err := dominocount.RuleSetByName(want.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name() != want.Name() {
+			t.Errorf("want rule set %s, got %s", want.Name(), got.Name())
+		}
+	}
+
+	_, err := dominocount.RuleSetByName("parchis")
+	if err == nil {
+		t.Error("want error looking up an unknown rule set")
+	}
+}
+
+func TestTo500RulesRoundHandPointsToFive(t *testing.T) {
+	t.Parallel()
+	testCases := map[int]int{0: 0, 23: 25, 42: 40, 45: 45}
+	for pips, want := range testCases {
+		got := dominocount.To500Rules.HandPoints(pips)
+		if got != want {
+			t.Errorf("want %d pips to score %d, got %d", pips, want, got)
+		}
+	}
+}
+
+func TestMatchWithRuleSetUsesRuleSetTarget(t *testing.T) {
+	t.Parallel()
+	m := dominocount.NewMatch(dominocount.MatchWithRuleSet(dominocount.CubanRules))
+	if m.Target != 100 {
+		t.Errorf("want cuban match to be played to 100, got %d", m.Target)
+	}
+
+	m = dominocount.NewMatch(dominocount.MatchWithRuleSet(dominocount.CubanRules), dominocount.MatchWithTargetScore(150))
+	if m.Target != 150 {
+		t.Errorf("want explicit target to override the rule set, got %d", m.Target)
+	}
+}
+
+func TestTranqueHandAwardsAllPipsToLowestTeam(t *testing.T) {
+	t.Parallel()
+	m := dominocount.NewMatch()
+	h, err := m.TranqueHand([]int{10, 12}, []int{5, 8}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Points2 != 35 || h.Points1 != 0 {
+		t.Errorf("want team 2 to take all 35 pips, got %d-%d", h.Points1, h.Points2)
+	}
+	if !h.Tranque {
+		t.Error("want hand to be marked as tranque")
+	}
+}
+
+func TestTranqueHandResolvesTiesByRuleSet(t *testing.T) {
+	t.Parallel()
+	m := dominocount.NewMatch()
+	h, err := m.TranqueHand([]int{10, 3}, []int{5, 8}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Points1 != 0 || h.Points2 != 0 {
+		t.Errorf("want dominican tie to award no points, got %d-%d", h.Points1, h.Points2)
+	}
+
+	m = dominocount.NewMatch(dominocount.MatchWithRuleSet(dominocount.PuertoRicanRules))
+	_, err = m.TranqueHand([]int{10, 3}, []int{5, 8}, "")
+	if err == nil {
+		t.Error("want error when the starter is needed and missing")
+	}
+	h, err = m.TranqueHand([]int{10, 3}, []int{5, 8}, dominocount.Team2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Points2 != 26 {
+		t.Errorf("want tie to go to the starting team, got %d-%d", h.Points1, h.Points2)
+	}
+}
+
+func TestTranqueHandErrorsWhenRuleSetHasNoTranque(t *testing.T) {
+	t.Parallel()
+	m := dominocount.NewMatch(dominocount.MatchWithRuleSet(dominocount.To500Rules))
+	_, err := m.TranqueHand([]int{10, 3}, []int{5, 8}, "")
+	if err == nil {
+		t.Error("want error settling a tranque without tranque rules")
+	}
+}
@@ -0,0 +1,161 @@
+package dominocount
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// seriesLengths are the lengths offered on the create series form.
+var seriesLengths = []int{3, 5, 7}
+
+// seriesForm is the data of the create series form.
+type seriesForm struct {
+	Team1Name, Team2Name string
+	BestOf               int
+	Lengths              []int
+	RuleSets             []RuleSet
+	Error                string
+}
+
+func newSeriesForm() seriesForm {
+	return seriesForm{BestOf: DefaultBestOf, Lengths: seriesLengths, RuleSets: RuleSets()}
+}
+
+// scoreboardPage is the data of the series page, the games won by each team
+// so far and one row per match played.
+type scoreboardPage struct {
+	*series
+	Editable   bool   `json:"-"`
+	EditSecret string `json:"-"`
+}
+
+// MatchURL returns where the scoreboard links the match to.
+func (p scoreboardPage) MatchURL(m match) string {
+	return matchLink(m, p.Editable, p.EditSecret)
+}
+
+func (s server) HandleSeriesForm() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render(w, r, seriesFormTemplate, newSeriesForm())
+	}
+}
+
+// HandleSeries renders the scoreboard of a series on GET and creates a series
+// on POST.
+func (s server) HandleSeries() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			s.handleGetSeries(w, r)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			s.handleCreateSeries(w, r)
+			return
+		}
+
+		http.Error(w, "method not supported", http.StatusBadRequest)
+	}
+}
+
+// handleCreateSeries creates the series with its first match and redirects to
+// the scoreboard. The browser keeps the edit secret like for a match.
+func (s server) handleCreateSeries(w http.ResponseWriter, r *http.Request) {
+	form := newSeriesForm()
+	form.Team1Name = r.PostFormValue("team1_name")
+	form.Team2Name = r.PostFormValue("team2_name")
+
+	bestOf, err := formParseBestOf(r)
+	if err != nil {
+		form.Error = "El número de juegos debe ser impar."
+		w.WriteHeader(http.StatusBadRequest)
+		render(w, r, seriesFormTemplate, form)
+		return
+	}
+	form.BestOf = bestOf
+	target, err := formParseTargetScore(r)
+	if err != nil {
+		form.Error = "Los puntos para ganar deben ser un número positivo."
+		w.WriteHeader(http.StatusBadRequest)
+		render(w, r, seriesFormTemplate, form)
+		return
+	}
+	rules, err := formParseRuleSet(r)
+	if err != nil {
+		form.Error = "Reglas desconocidas."
+		w.WriteHeader(http.StatusBadRequest)
+		render(w, r, seriesFormTemplate, form)
+		return
+	}
+
+	sr := NewSeries(
+		SeriesWithTeam1Name(form.Team1Name),
+		SeriesWithTeam2Name(form.Team2Name),
+		SeriesWithBestOf(bestOf),
+		SeriesWithRuleSet(rules),
+		SeriesWithTargetScore(target),
+		SeriesWithOwner(currentUserID(r)),
+	)
+	err = s.store.CreateSeries(&sr)
+	if err != nil {
+		form.Error = "Los equipos deben ser distintos y el número de juegos impar."
+		w.WriteHeader(http.StatusBadRequest)
+		render(w, r, seriesFormTemplate, form)
+		return
+	}
+	setEditCookie(w, sr.Slug, sr.EditSecret)
+	http.Redirect(w, r, sr.Slug, http.StatusSeeOther)
+}
+
+// handleGetSeries renders the scoreboard. As on the match page, a secret sent
+// as clave in the query is remembered in a cookie.
+func (s server) handleGetSeries(w http.ResponseWriter, r *http.Request) {
+	sr, err := s.store.GetSeriesBySlug(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if sr.ID == 0 {
+		http.Error(w, "series not found", http.StatusNotFound)
+		return
+	}
+
+	page := scoreboardPage{series: sr}
+	secret := editSecret(r)
+	err = s.store.CheckSeriesEditAccess(sr.ID, secret, currentUserID(r))
+	switch err.(type) {
+	case nil:
+		if r.URL.Query().Get("clave") != "" {
+			setEditCookie(w, sr.Slug, secret)
+		}
+		page.Editable = true
+		page.EditSecret = secret
+	case *EditForbiddenError:
+	default:
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, seriesTemplate, page)
+}
+
+// formParseBestOf returns the length chosen on the create series form, 0 when
+// none was provided so the default applies.
+func formParseBestOf(r *http.Request) (int, error) {
+	bestOfString := r.PostFormValue("best_of")
+	if bestOfString == "" {
+		return 0, nil
+	}
+
+	bestOf, err := strconv.Atoi(bestOfString)
+	if err != nil {
+		return 0, errors.New("not able to parse series length")
+	}
+	if bestOf < 1 || bestOf%2 == 0 {
+		return 0, errors.New("series length must be a positive odd number")
+	}
+	return bestOf, nil
+}
@@ -0,0 +1,174 @@
+package dominocount
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// DefaultBestOf is the length of a series when none is chosen, first to win
+// three matches.
+const DefaultBestOf = 5
+
+// series is a run of matches between the same two teams, won by the first
+// team to take most of BestOf matches. Each match is scored as a regular
+// match and the next one is created when it ends.
+type series struct {
+	ID        int64     `json:"-"`
+	Slug      string    `json:"id"`
+	Team1     string    `json:"team1"`
+	Team2     string    `json:"team2"`
+	BestOf    int       `json:"best_of"`
+	Rules     RuleSet   `json:"rule_set"`
+	Target    int       `json:"target_score"`
+	Matches   []match   `json:"matches"`
+	CreatedAt time.Time `json:"created_at"`
+	OwnerID   int64     `json:"-"`
+	// EditSecret works like the edit secret of a match, for the series and
+	// all of its matches.
+	EditSecret string `json:"-"`
+}
+
+type seriesOption func(*series) error
+
+func NewSeries(opts ...seriesOption) series {
+	s := series{
+		Team1:     string(Team1),
+		Team2:     string(Team2),
+		BestOf:    DefaultBestOf,
+		Rules:     DominicanRules,
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, opt := range opts {
+		//ignoring errors as current options generate no errors
+		opt(&s)
+	}
+	if s.Target == 0 {
+		s.Target = s.Rules.TargetScore()
+	}
+	return s
+}
+
+func SeriesWithTeam1Name(name string) seriesOption {
+	return func(s *series) error {
+		if name = strings.TrimSpace(name); name != "" {
+			s.Team1 = name
+		}
+		return nil
+	}
+}
+
+func SeriesWithTeam2Name(name string) seriesOption {
+	return func(s *series) error {
+		if name = strings.TrimSpace(name); name != "" {
+			s.Team2 = name
+		}
+		return nil
+	}
+}
+
+// SeriesWithBestOf sets how many matches the series lasts at most. Values
+// below 1 keep the default.
+func SeriesWithBestOf(n int) seriesOption {
+	return func(s *series) error {
+		if n > 0 {
+			s.BestOf = n
+		}
+		return nil
+	}
+}
+
+// SeriesWithRuleSet sets the variant every match of the series is scored
+// with. A nil rule set keeps the default dominican rules.
+func SeriesWithRuleSet(r RuleSet) seriesOption {
+	return func(s *series) error {
+		if r != nil {
+			s.Rules = r
+		}
+		return nil
+	}
+}
+
+// SeriesWithTargetScore sets the target of every match. Values below 1 keep
+// the target of the rule set.
+func SeriesWithTargetScore(target int) seriesOption {
+	return func(s *series) error {
+		if target > 0 {
+			s.Target = target
+		}
+		return nil
+	}
+}
+
+func SeriesWithOwner(userID int64) seriesOption {
+	return func(s *series) error {
+		s.OwnerID = userID
+		return nil
+	}
+}
+
+// validate checks the series can be decided.
+func (s series) validate() error {
+	if s.BestOf%2 == 0 {
+		return errors.New("a series must last an odd number of matches")
+	}
+	if strings.EqualFold(s.Team1, s.Team2) {
+		return errors.New("a series needs two different teams")
+	}
+	return nil
+}
+
+// WinsNeeded returns the matches a team has to win to take the series.
+func (s series) WinsNeeded() int {
+	return s.BestOf/2 + 1
+}
+
+// seriesWins counts the matches won by each team.
+func seriesWins(matches []match) (wins1, wins2 int) {
+	for _, m := range matches {
+		switch m.Winner() {
+		case Team1:
+			wins1++
+		case Team2:
+			wins2++
+		}
+	}
+	return wins1, wins2
+}
+
+// Winner returns the team that took the series or an empty team while it is
+// being played.
+func (s series) Winner() team {
+	wins1, wins2 := seriesWins(s.Matches)
+	switch {
+	case wins1 >= s.WinsNeeded():
+		return Team1
+	case wins2 >= s.WinsNeeded():
+		return Team2
+	}
+	return ""
+}
+
+// WinnerName returns the name of the team that took the series, empty if
+// there is none yet.
+func (s series) WinnerName() string {
+	switch s.Winner() {
+	case Team1:
+		return s.Team1
+	case Team2:
+		return s.Team2
+	}
+	return ""
+}
+
+// Wins1 returns the matches won so far by the first team.
+func (s series) Wins1() int {
+	wins1, _ := seriesWins(s.Matches)
+	return wins1
+}
+
+// Wins2 returns the matches won so far by the second team.
+func (s series) Wins2() int {
+	_, wins2 := seriesWins(s.Matches)
+	return wins2
+}
@@ -0,0 +1,165 @@
+package dominocount_test
+
+import (
+	"dominocount"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSQLiteStore_SeriesCreatesNextMatchUntilDecided(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := dominocount.NewSeries(
+		dominocount.SeriesWithTeam1Name("Los Primos"),
+		dominocount.SeriesWithTeam2Name("La Esquina"),
+		dominocount.SeriesWithBestOf(3),
+		dominocount.SeriesWithTargetScore(100),
+	)
+	err = store.CreateSeries(&sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.GetSeriesBySlug(sr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Matches) != 1 {
+		t.Fatalf("want the first match created with the series, got %d matches", len(got.Matches))
+	}
+	first := got.Matches[0]
+	if first.Team1 != "Los Primos" || first.Team2 != "La Esquina" || first.Target != 100 {
+		t.Errorf("want the first match between the series teams to 100, got %+v", first)
+	}
+	err = store.CheckEditAccess(first.Id, sr.EditSecret, 0)
+	if err != nil {
+		t.Errorf("want the series secret to open its matches, got %s", err)
+	}
+
+	_, err = store.AddHandByID(first.Id, dominocount.NewHand(100, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetSeriesBySlug(sr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Matches) != 2 || got.Wins1() != 1 || got.Wins2() != 0 {
+		t.Fatalf("want a second match after the first win, got %d matches %d-%d", len(got.Matches), got.Wins1(), got.Wins2())
+	}
+
+	_, err = store.UndoLastHand(first.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetSeriesBySlug(sr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Matches) != 1 {
+		t.Fatalf("want the unplayed second match removed after the undo, got %d matches", len(got.Matches))
+	}
+
+	_, err = store.AddHandByID(first.Id, dominocount.NewHand(100, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetSeriesBySlug(sr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := got.Matches[1]
+	_, err = store.AbandonMatch(second.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetSeriesBySlug(sr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Matches) != 3 {
+		t.Fatalf("want the abandoned match replayed, got %d matches", len(got.Matches))
+	}
+
+	_, err = store.AddHandByID(got.Matches[2].Id, dominocount.NewHand(100, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetSeriesBySlug(sr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.WinnerName() != "Los Primos" || len(got.Matches) != 3 {
+		t.Errorf("want Los Primos to take the series 2-0 with no more matches, got %q after %d matches", got.WinnerName(), len(got.Matches))
+	}
+
+	_, err = store.UndoLastHand(first.Id)
+	if _, ok := err.(*dominocount.SeriesAdvancedError); !ok {
+		t.Errorf("want SeriesAdvancedError undoing a match the series moved on from, got %v", err)
+	}
+}
+
+func TestSQLiteStore_CreateSeriesValidates(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := dominocount.NewSeries(dominocount.SeriesWithBestOf(4))
+	err = store.CreateSeries(&sr)
+	if err == nil {
+		t.Error("want an error creating a series of an even number of matches")
+	}
+	sr = dominocount.NewSeries(dominocount.SeriesWithTeam1Name("Ellos"), dominocount.SeriesWithTeam2Name("ellos"))
+	err = store.CreateSeries(&sr)
+	if err == nil {
+		t.Error("want an error creating a series of a team against itself")
+	}
+}
+
+func TestSeriesPageShowsMatchesWon(t *testing.T) {
+	t.Parallel()
+	client, testServer, _ := newAccountTestClient(t)
+
+	res, err := client.PostForm(testServer.URL+"/series/", map[string][]string{
+		"team1_name": {"Los Primos"},
+		"team2_name": {"La Esquina"},
+		"best_of":    {"5"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || !strings.Contains(string(body), "al mejor de 5") || !strings.Contains(string(body), "anotar") {
+		t.Fatalf("want the scoreboard with a match to score, got %d\n%s", res.StatusCode, body)
+	}
+
+	res, err = client.PostForm(testServer.URL+"/series/", map[string][]string{
+		"team1_name": {"Los Primos"},
+		"team2_name": {"La Esquina"},
+		"best_of":    {"4"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("want status 400 for an even series length, got %d", res.StatusCode)
+	}
+
+	res, err = client.Get(testServer.URL + "/series/nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("want status 404 for an unknown series, got %d", res.StatusCode)
+	}
+}
@@ -2,14 +2,18 @@ package dominocount
 
 import (
 	"embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/mitchellh/go-homedir"
@@ -22,10 +26,12 @@ func NewServer(store sqliteStore, options ...serverOption) (server, error) {
 		return server{}, err
 	}
 
+	events := newHub()
 	s := server{
 		Server:     &http.Server{Addr: defaultAddress},
 		output:     os.Stdout,
-		store:      &store,
+		store:      liveStore{Storage: &store, hub: events},
+		hub:        events,
 		fileServer: http.FileServer(http.FS(assets)),
 	}
 
@@ -61,16 +67,7 @@ func ServerWithOutput(output io.Writer) serverOption {
 
 // RunServer configures and starts a dominocount server on localhost port 8080
 func RunServer(output io.Writer) {
-	storeDir := os.Getenv(dbVolume)
-	if storeDir == "" {
-		homeDir, err := homedir.Dir()
-		if err != nil {
-			fmt.Fprintln(output, err)
-		}
-		storeDir = homeDir
-	}
-
-	store, err := OpenSQLiteStore(storeDir + "/.dominoCount.db")
+	store, err := OpenSQLiteStore(storePath(output))
 	if err != nil {
 		fmt.Fprintln(output, err)
 	}
@@ -89,6 +86,46 @@ func RunServer(output io.Writer) {
 	server.Run()
 
 }
+
+// RunIssueEditSecrets gives an edit secret to the matches of the store used
+// by RunServer that were created before edit secrets, and writes the link
+// that opens each one for editing.
+func RunIssueEditSecrets(output io.Writer) {
+	store, err := OpenSQLiteStore(storePath(output))
+	if err != nil {
+		fmt.Fprintln(output, err)
+		return
+	}
+	secrets, err := store.IssueEditSecrets()
+	if err != nil {
+		fmt.Fprintln(output, err)
+		return
+	}
+	slugs := make([]string, 0, len(secrets))
+	for slug := range secrets {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	for _, slug := range slugs {
+		fmt.Fprintf(output, "/match/%s?clave=%s\n", slug, secrets[slug])
+	}
+	fmt.Fprintf(output, "edit secrets issued for %d matches\n", len(secrets))
+}
+
+// storePath returns where the database lives, in the directory set in
+// SQLITE_VOLUME or else in the home directory.
+func storePath(output io.Writer) string {
+	storeDir := os.Getenv(dbVolume)
+	if storeDir == "" {
+		homeDir, err := homedir.Dir()
+		if err != nil {
+			fmt.Fprintln(output, err)
+		}
+		storeDir = homeDir
+	}
+	return storeDir + "/.dominoCount.db"
+}
+
 func (s *server) Run() {
 	fmt.Fprintln(s.output, "starting http server")
 
@@ -104,16 +141,52 @@ func (s *server) Routes() http.Handler {
 	router := mux.NewRouter()
 	router.HandleFunc("/", s.HandleIndex())
 	router.HandleFunc("/match/create", s.HandleMatchForm())
+	router.HandleFunc("/matches", s.HandleMatches())
 	router.HandleFunc("/match/", s.HandleMatch())
 	router.HandleFunc("/match/{id}", s.HandleMatch())
+	router.HandleFunc("/match/{id}/undo", s.HandleUndo())
+	router.HandleFunc("/match/{id}/abandon", s.HandleAbandon())
+	router.HandleFunc("/match/{id}/restore", s.HandleRestore())
+	router.HandleFunc("/match/{id}/hands/{number}", s.HandleHand())
+	router.HandleFunc("/match/{id}/events", s.HandleEvents())
+	router.HandleFunc("/watch/{token}", s.HandleWatch())
+	router.HandleFunc("/watch/{token}/events", s.HandleWatchEvents())
+	router.HandleFunc("/tournaments", s.HandleTournaments())
+	router.HandleFunc("/tournament/create", s.HandleTournamentForm())
+	router.HandleFunc("/tournament/", s.HandleTournament())
+	router.HandleFunc("/tournament/{id}", s.HandleTournament())
+	router.HandleFunc("/series/create", s.HandleSeriesForm())
+	router.HandleFunc("/series/", s.HandleSeries())
+	router.HandleFunc("/series/{id}", s.HandleSeries())
+	router.HandleFunc("/leagues", s.HandleLeagues())
+	router.HandleFunc("/leagues/create", s.HandleLeagueForm())
+	router.HandleFunc("/leagues/{id}", s.HandleLeague())
+	router.HandleFunc("/leagues/{id}/seasons", s.HandleSeasons())
+	router.HandleFunc("/ratings", s.HandleRatings())
+	router.HandleFunc("/ratings/players/{id}", s.HandleRatingHistory())
+	router.HandleFunc("/stats/players/{id}", s.HandlePlayerStats())
+	router.HandleFunc("/stats/teams/{name}", s.HandleTeamStats())
+	router.HandleFunc("/h2h", s.HandleHeadToHead())
+	router.HandleFunc("/signup", s.HandleSignup())
+	router.HandleFunc("/login", s.HandleLogin())
+	router.HandleFunc("/logout", s.HandleLogout())
+	router.HandleFunc("/players", s.HandlePlayers())
+	router.HandleFunc("/players/{id}", s.HandlePlayer())
+	router.HandleFunc("/players/{id}/merge", s.HandleMergePlayer())
+	router.HandleFunc("/api/openapi.json", s.HandleOpenAPI())
+	router.HandleFunc("/api/v1/matches", s.HandleAPIMatches())
+	router.HandleFunc("/api/v1/matches/{id}", s.HandleAPIMatch())
+	router.HandleFunc("/api/v1/matches/{id}/hands", s.HandleAPIHands())
 	router.Handle("/static/{file}", http.StripPrefix("/static", s.fileServer))
 
+	router.Use(s.withUser)
+
 	return router
 }
 
 func (s server) HandleIndex() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		render(w, r, indexTemplate, nil)
+		render(w, r, indexTemplate, currentUser(r))
 	}
 }
 
@@ -135,21 +208,293 @@ func (s server) HandleMatch() http.HandlerFunc {
 			return
 		}
 
+		if r.Method == http.MethodDelete {
+			s.handleDeleteMatch(w, r)
+			return
+		}
+
 		http.Error(w, "method not supported", http.StatusBadRequest)
 
 	}
 }
 
+// HandleUndo removes the last hand of a match and renders the updated table.
+func (s server) HandleUndo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		id, ok := s.editableMatchID(w, r)
+		if !ok {
+			return
+		}
+
+		m, err := s.store.UndoLastHand(id)
+		if err != nil {
+			switch err.(type) {
+			case *HandNotFoundError:
+				http.Error(w, "no hands to undo", http.StatusNotFound)
+				return
+			case *MatchArchivedError:
+				http.Error(w, "match is archived, restore it to undo hands", http.StatusConflict)
+				return
+			case *TournamentAdvancedError, *SeriesAdvancedError:
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		render(w, r, matchTableTemplate, m)
+	}
+}
+
+// HandleAbandon closes a match without a winner and redirects back to it.
+func (s server) HandleAbandon() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		id, ok := s.editableMatchID(w, r)
+		if !ok {
+			return
+		}
+
+		_, err := s.store.AbandonMatch(id)
+		if err != nil {
+			_, ok := err.(*GameOverError)
+			if ok {
+				http.Error(w, "match is already over", http.StatusConflict)
+				return
+			}
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		matchURL := "/match/" + mux.Vars(r)["id"]
+		http.Redirect(w, r, matchURL, http.StatusSeeOther)
+	}
+}
+
+// HandleRestore brings an archived match back and redirects to it.
+func (s server) HandleRestore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		id, ok := s.editableMatchID(w, r)
+		if !ok {
+			return
+		}
+
+		err := s.store.RestoreMatch(id)
+		if err != nil {
+			_, ok := err.(*MatchNotFoundError)
+			if ok {
+				http.Error(w, "Match Not Found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		matchURL := "/match/" + mux.Vars(r)["id"]
+		http.Redirect(w, r, matchURL, http.StatusSeeOther)
+	}
+}
+
+// HandleHand renders the edit form of a single hand on GET and amends its
+// points on PATCH.
+func (s server) HandleHand() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			s.handleGetHand(w, r)
+			return
+		}
+
+		if r.Method == http.MethodPatch {
+			s.handlePatchHand(w, r)
+			return
+		}
+
+		http.Error(w, "method not supported", http.StatusBadRequest)
+	}
+}
+
+// HandlePlayers lists the registered players on GET and registers a new one
+// on POST, for logged in users. htmx requests get the list as datalist options for autocompletion,
+// filtered by the q prefix.
+func (s server) HandlePlayers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			players, err := s.store.ListPlayers(r.URL.Query().Get("q"))
+			if err != nil {
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			if r.Header.Get("HX-Request") != "" {
+				render(w, r, playerOptionsTemplate, players)
+				return
+			}
+			render(w, r, playersTemplate, players)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			if !requireUser(w, r) {
+				return
+			}
+			_, err := s.store.CreatePlayer(r.PostFormValue("name"))
+			if err != nil {
+				writePlayerError(w, err)
+				return
+			}
+			http.Redirect(w, r, "/players", http.StatusSeeOther)
+			return
+		}
+
+		http.Error(w, "method not supported", http.StatusBadRequest)
+	}
+}
+
+// HandlePlayer renames a registered player on PATCH, for logged in users.
+func (s server) HandlePlayer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		if !requireUser(w, r) {
+			return
+		}
+		id, err := queryStringParseID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = s.store.RenamePlayer(id, r.PostFormValue("name"))
+		if err != nil {
+			writePlayerError(w, err)
+			return
+		}
+		w.Header().Set("HX-Redirect", "/players")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// HandleMergePlayer merges the player into the one sent as into and redirects
+// to the player list. Only logged in users can merge players.
+func (s server) HandleMergePlayer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		if !requireUser(w, r) {
+			return
+		}
+		id, err := queryStringParseID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		into, err := strconv.ParseInt(r.PostFormValue("into"), 10, 64)
+		if err != nil {
+			http.Error(w, "not able to parse player to merge into", http.StatusBadRequest)
+			return
+		}
+
+		err = s.store.MergePlayers(id, into)
+		if err != nil {
+			writePlayerError(w, err)
+			return
+		}
+		http.Redirect(w, r, "/players", http.StatusSeeOther)
+	}
+}
+
+func writePlayerError(w http.ResponseWriter, err error) {
+	switch err.(type) {
+	case *PlayerNotFoundError:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case *PlayerExistsError, *PlayersShareMatchError:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// HandleMatches renders a page of matches filtered by the query string.
+func (s server) HandleMatches() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mine") != "" && currentUser(r) == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		filter, err := queryStringParseMatchFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		matches, next, err := s.store.ListMatches(filter)
+		if err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		list := matchList{Query: r.URL.Query(), Matches: matches, User: currentUser(r)}
+		if next != 0 {
+			query := r.URL.Query()
+			query.Set("cursor", strconv.FormatInt(next, 10))
+			list.NextURL = "/matches?" + query.Encode()
+		}
+		render(w, r, matchesTemplate, list)
+	}
+}
+
+// handForm is the data of the inline form editing a hand. Like the form
+// adding hands, it takes the raw points and the bonuses claimed.
+type handForm struct {
+	hand
+	Slug         string
+	Bonuses      []bonus
+	PlaysTranque bool
+}
+
+// matchList is the data rendered by the match listing page.
+type matchList struct {
+	Query   url.Values `json:"-"`
+	Matches []match    `json:"matches"`
+	NextURL string     `json:"next,omitempty"`
+	User    *user      `json:"-"`
+}
+
 func (s *server) HandleMatchForm() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		render(w, r, formMatchTemplate, nil)
+		render(w, r, formMatchTemplate, newMatchForm())
 	}
 }
 
+// matchForm is the data rendered by the create match form.
+type matchForm struct {
+	Team1Name, Team2Name string
+	RuleSets             []RuleSet
+	Error                string
+}
+
+func newMatchForm() matchForm {
+	return matchForm{RuleSets: RuleSets()}
+}
+
+// handleGetMatch renders the scorekeeper page of the match. Visitors without
+// the edit secret are sent to the spectator view instead. A secret sent as
+// clave in the query is remembered in a cookie, so the link shown on the page
+// hands edit rights to another device.
 func (s *server) handleGetMatch(w http.ResponseWriter, r *http.Request) {
-	id, err := queryStringParseID(r)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	id, ok := s.matchID(w, r)
+	if !ok {
 		return
 	}
 
@@ -159,10 +504,21 @@ func (s *server) handleGetMatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if m.Id == 0 {
-		http.Error(w, "Match Not Found", http.StatusNotFound)
+	secret := editSecret(r)
+	err = s.store.CheckEditAccess(id, secret, currentUserID(r))
+	if err != nil {
+		_, ok := err.(*EditForbiddenError)
+		if ok {
+			http.Redirect(w, r, "/watch/"+m.WatchToken, http.StatusSeeOther)
+			return
+		}
+		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
+	if r.URL.Query().Get("clave") != "" {
+		setEditCookie(w, m.Slug, secret)
+	}
+	m.EditSecret = secret
 
 	render(w, r, matchTemplate, m)
 }
@@ -170,25 +526,144 @@ func (s *server) handleGetMatch(w http.ResponseWriter, r *http.Request) {
 func (s server) handleCreateMatch(w http.ResponseWriter, r *http.Request) {
 	team1Name := r.PostFormValue("team1_name")
 	team2Name := r.PostFormValue("team2_name")
+	form := newMatchForm()
+	form.Team1Name, form.Team2Name = team1Name, team2Name
+
+	target, err := formParseTargetScore(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render(w, r, formMatchTemplate, form)
+		return
+	}
+	rules, err := formParseRuleSet(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render(w, r, formMatchTemplate, form)
+		return
+	}
 
-	m := NewMatch(MatchWithTeam1Name(team1Name), MatchWithTeam2Name(team2Name))
-	err := s.store.CreateMatch(&m)
+	m := NewMatch(
+		MatchWithTeam1Name(team1Name),
+		MatchWithTeam2Name(team2Name),
+		MatchWithRuleSet(rules),
+		MatchWithTargetScore(target),
+		MatchWithPlayer(SeatNorth, r.PostFormValue("north_name")),
+		MatchWithPlayer(SeatSouth, r.PostFormValue("south_name")),
+		MatchWithPlayer(SeatEast, r.PostFormValue("east_name")),
+		MatchWithPlayer(SeatWest, r.PostFormValue("west_name")),
+		MatchWithOwner(currentUserID(r)),
+	)
+	err = s.store.CreateMatch(&m)
 	if err != nil {
+		if _, ok := err.(*PlayersShareMatchError); ok {
+			form.Error = "un jugador no puede ocupar dos asientos"
+		}
 		w.WriteHeader(http.StatusBadRequest)
-		render(w, r, formMatchTemplate, nil)
+		render(w, r, formMatchTemplate, form)
 		return
 	}
-	matchURL := fmt.Sprintf("%d", m.Id)
-	http.Redirect(w, r, matchURL, http.StatusSeeOther)
+	setEditCookie(w, m.Slug, m.EditSecret)
+	http.Redirect(w, r, m.Slug, http.StatusSeeOther)
 }
 
+// handlePatchMatch adds the hand sent on one of the match forms. It needs the
+// edit secret of the match, and requests made through a spectator link are
+// refused.
 func (s server) handlePatchMatch(w http.ResponseWriter, r *http.Request) {
-	id, err := queryStringParseID(r)
+	if spectatorToken(r) != "" {
+		http.Error(w, "spectator links are read-only", http.StatusForbidden)
+		return
+	}
+	id, ok := s.editableMatchID(w, r)
+	if !ok {
+		return
+	}
+
+	var h hand
+	var err error
+	switch r.PostFormValue("mode") {
+	case "tranque":
+		h, err = s.formParseTranque(r, id)
+	case "tiles":
+		h, err = s.formParseTiles(r, id)
+	default:
+		h, err = formParsePoints(r)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m, err := s.store.AddHandByID(id, h)
+	if err != nil {
+		switch err.(type) {
+		case *GameOverError:
+			http.Error(w, "match is over, no more points can be added", http.StatusConflict)
+		case *MatchArchivedError:
+			http.Error(w, "match is archived, restore it to add points", http.StatusConflict)
+		default:
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+	render(w, r, matchTableTemplate, m)
+}
+
+// handleDeleteMatch archives the match and sends the browser to the listing.
+func (s server) handleDeleteMatch(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.editableMatchID(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.store.DeleteMatch(id)
+	if err != nil {
+		_, ok := err.(*MatchNotFoundError)
+		if ok {
+			http.Error(w, "Match Not Found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Redirect", "/matches")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s server) handleGetHand(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.matchID(w, r)
+	if !ok {
+		return
+	}
+	number, err := queryStringParseHandNumber(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	m, err := s.store.GetMatchByID(id)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	i := m.handIndex(number)
+	if i < 0 {
+		http.Error(w, "Hand Not Found", http.StatusNotFound)
+		return
+	}
+	render(w, r, handFormTemplate, handForm{hand: m.Hands[i], Slug: m.Slug, Bonuses: m.Bonuses(), PlaysTranque: m.PlaysTranque()})
+}
+
+func (s server) handlePatchHand(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.editableMatchID(w, r)
+	if !ok {
+		return
+	}
+	number, err := queryStringParseHandNumber(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	score1, err := formParseScore(r, "team1_points")
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -199,23 +674,109 @@ func (s server) handlePatchMatch(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	h, err := formParseBonuses(r, NewHand(score1, score2))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.Tranque = r.PostFormValue("tranque") != ""
 
-	m, err := s.store.AddPointsByID(id, score1, score2)
+	m, err := s.store.UpdateHand(id, number, h)
 	if err != nil {
-		_, ok := err.(*GameOverError)
-		if !ok {
-			http.Error(w, "internal server error", http.StatusInternalServerError)
-			return
-		}
-		m, err = s.store.GetMatchByID(id)
-		if err != nil {
+		switch err.(type) {
+		case *HandNotFoundError:
+			http.Error(w, "Hand Not Found", http.StatusNotFound)
+		case *MatchArchivedError:
+			http.Error(w, "match is archived, restore it to edit hands", http.StatusConflict)
+		case *TournamentAdvancedError, *SeriesAdvancedError:
+			http.Error(w, err.Error(), http.StatusConflict)
+		case *NegativePointsError:
 			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+		default:
+			http.Error(w, "internal server error", http.StatusInternalServerError)
 		}
+		return
 	}
 	render(w, r, matchTableTemplate, m)
 }
 
+// queryStringParseMatchID resolves the match slug in the path to the match ID.
+func (s server) queryStringParseMatchID(r *http.Request) (int64, error) {
+	slug := mux.Vars(r)["id"]
+	if slug == "" {
+		return 0, errors.New("no match ID provided")
+	}
+	return s.store.GetMatchIDBySlug(slug)
+}
+
+// matchID is queryStringParseMatchID for the HTML handlers. On failure the
+// error has been written and ok is false.
+func (s server) matchID(w http.ResponseWriter, r *http.Request) (id int64, ok bool) {
+	id, err := s.queryStringParseMatchID(r)
+	if err != nil {
+		writeMatchError(w, err)
+		return 0, false
+	}
+	return id, true
+}
+
+// editableMatchID is matchID for requests that change the match, they must
+// carry its edit secret.
+func (s server) editableMatchID(w http.ResponseWriter, r *http.Request) (id int64, ok bool) {
+	id, ok = s.matchID(w, r)
+	if !ok {
+		return 0, false
+	}
+	err := s.store.CheckEditAccess(id, editSecret(r), currentUserID(r))
+	if err != nil {
+		writeMatchError(w, err)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeMatchError(w http.ResponseWriter, err error) {
+	switch err.(type) {
+	case *MatchNotFoundError:
+		http.Error(w, "Match Not Found", http.StatusNotFound)
+	case *EditForbiddenError:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// editSecret returns the edit secret sent with the request, by API clients
+// in the X-Edit-Secret header, by browsers in the cookie of the match or once
+// as clave in the query.
+func editSecret(r *http.Request) string {
+	secret := r.Header.Get("X-Edit-Secret")
+	if secret != "" {
+		return secret
+	}
+	cookie, err := r.Cookie(editCookieName(mux.Vars(r)["id"]))
+	if err == nil {
+		return cookie.Value
+	}
+	return r.URL.Query().Get("clave")
+}
+
+func editCookieName(slug string) string {
+	return "edit_" + slug
+}
+
+// setEditCookie remembers in the browser the edit secret of the match.
+func setEditCookie(w http.ResponseWriter, slug, secret string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     editCookieName(slug),
+		Value:    secret,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
 func queryStringParseID(r *http.Request) (int64, error) {
 	matchId := mux.Vars(r)["id"]
 	if matchId == "" {
@@ -230,6 +791,64 @@ func queryStringParseID(r *http.Request) (int64, error) {
 
 }
 
+// queryStringParseMatchFilter reads the match listing filters, dates are
+// days formatted as 2006-01-02 and to includes the whole day.
+func queryStringParseMatchFilter(r *http.Request) (MatchFilter, error) {
+	query := r.URL.Query()
+	filter := MatchFilter{
+		Team:     query.Get("team"),
+		Player:   query.Get("player"),
+		Status:   matchStatus(query.Get("status")),
+		Archived: query.Get("archived") != "",
+	}
+	if query.Get("mine") != "" {
+		filter.Owner = currentUserID(r)
+		if filter.Owner == 0 {
+			return MatchFilter{}, errors.New("log in to list your matches")
+		}
+	}
+	switch filter.Status {
+	case "", StatusInProgress, StatusFinished, StatusAbandoned:
+	default:
+		return MatchFilter{}, errors.New("unknown match status")
+	}
+
+	var err error
+	if from := query.Get("from"); from != "" {
+		filter.From, err = time.Parse(dateLayout, from)
+		if err != nil {
+			return MatchFilter{}, errors.New("not able to parse from date")
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		filter.To, err = time.Parse(dateLayout, to)
+		if err != nil {
+			return MatchFilter{}, errors.New("not able to parse to date")
+		}
+		filter.To = filter.To.AddDate(0, 0, 1)
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		filter.Cursor, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			return MatchFilter{}, errors.New("not able to parse cursor")
+		}
+	}
+	return filter, nil
+}
+
+func queryStringParseHandNumber(r *http.Request) (int, error) {
+	handNumber := mux.Vars(r)["number"]
+	if handNumber == "" {
+		return 0, errors.New("no hand number provided")
+	}
+
+	number, err := strconv.Atoi(handNumber)
+	if err != nil {
+		return 0, errors.New("not able to parse hand number")
+	}
+	return number, nil
+}
+
 func formParseScore(r *http.Request, id string) (int, error) {
 	scoreString := r.PostFormValue(id)
 	if scoreString == "" {
@@ -244,10 +863,164 @@ func formParseScore(r *http.Request, id string) (int, error) {
 
 }
 
+// formParsePoints returns the hand entered on the points form, the points of
+// each team plus the bonuses claimed.
+func formParsePoints(r *http.Request) (hand, error) {
+	score1, err := formParseScore(r, "team1_points")
+	if err != nil {
+		return hand{}, err
+	}
+	score2, err := formParseScore(r, "team2_points")
+	if err != nil {
+		return hand{}, err
+	}
+	h, err := formParseDomino(r, NewHand(score1, score2))
+	if err != nil {
+		return hand{}, err
+	}
+	return formParseBonuses(r, h)
+}
+
+// formParseDomino records on h the seat of the player who won the hand, sent
+// as domino. The field is optional.
+func formParseDomino(r *http.Request, h hand) (hand, error) {
+	domino := r.PostFormValue("domino")
+	if domino == "" {
+		return h, nil
+	}
+	s, err := parseSeat(domino)
+	if err != nil {
+		return hand{}, err
+	}
+	return h.WonBy(s), nil
+}
+
+// formParseTranque settles the blocked game entered on the tranque form with
+// the rules of the match. Each team sends the remaining pips of its two
+// players as repeated team1_pips and team2_pips values.
+func (s server) formParseTranque(r *http.Request, id int64) (hand, error) {
+	m, err := s.store.GetMatchByID(id)
+	if err != nil {
+		return hand{}, err
+	}
+	if m.Id == 0 {
+		return hand{}, errors.New("match not found")
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		return hand{}, errors.New("not able to parse form")
+	}
+	pips := [2][]int{}
+	for i, field := range []string{"team1_pips", "team2_pips"} {
+		for _, value := range r.PostForm[field] {
+			p, err := strconv.Atoi(value)
+			if err != nil {
+				return hand{}, errors.New("not able to parse pips")
+			}
+			pips[i] = append(pips[i], p)
+		}
+	}
+	return m.TranqueHand(pips[0], pips[1], team(r.PostFormValue("starter")))
+}
+
+// formParseTiles counts the leftover tiles picked on the tile form, sent as
+// repeated "a-b" tiles values, and gives their pips to the winner team or to
+// the team of the player who dominated.
+func (s server) formParseTiles(r *http.Request, id int64) (hand, error) {
+	m, err := s.store.GetMatchByID(id)
+	if err != nil {
+		return hand{}, err
+	}
+	if m.Id == 0 {
+		return hand{}, errors.New("match not found")
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		return hand{}, errors.New("not able to parse form")
+	}
+	pips, err := CountTiles(r.PostForm["tiles"], m.Rules.MaxPip())
+	if err != nil {
+		return hand{}, err
+	}
+
+	h, err := formParseDomino(r, NewHand(0, 0))
+	if err != nil {
+		return hand{}, err
+	}
+	winner := team(r.PostFormValue("winner"))
+	if winner == "" && h.Domino != "" {
+		winner = h.Domino.Team()
+	}
+	switch winner {
+	case Team1:
+		h.Points1 = pips
+	case Team2:
+		h.Points2 = pips
+	default:
+		return hand{}, errors.New("no winner team provided")
+	}
+	return formParseBonuses(r, h)
+}
+
+// formParseBonuses adds to h the bonuses ticked for each team on the points
+// form, sent as repeated team1_bonus and team2_bonus values.
+func formParseBonuses(r *http.Request, h hand) (hand, error) {
+	err := r.ParseForm()
+	if err != nil {
+		return hand{}, errors.New("not able to parse form")
+	}
+
+	fields := []struct {
+		name string
+		team team
+	}{{"team1_bonus", Team1}, {"team2_bonus", Team2}}
+	for _, field := range fields {
+		for _, value := range r.PostForm[field.name] {
+			kind, err := parseBonus(value)
+			if err != nil {
+				return hand{}, err
+			}
+			h = h.WithBonus(field.team, kind)
+		}
+	}
+	return h, nil
+}
+
+// formParseTargetScore returns the target score of the create match form, 0
+// when none was provided so the match default applies.
+func formParseTargetScore(r *http.Request) (int, error) {
+	targetString := r.PostFormValue("target_score")
+	if targetString == "" {
+		return 0, nil
+	}
+
+	target, err := strconv.ParseInt(targetString, 10, 32)
+	if err != nil {
+		return 0, errors.New("not able to parse target score")
+	}
+	if target < 1 {
+		return 0, errors.New("target score must be positive")
+	}
+	return int(target), nil
+}
+
+// formParseRuleSet returns the rule set chosen on the create match form, nil
+// when none was provided so the match default applies.
+func formParseRuleSet(r *http.Request) (RuleSet, error) {
+	name := r.PostFormValue("rule_set")
+	if name == "" {
+		return nil, nil
+	}
+	return RuleSetByName(name)
+}
+
 type server struct {
 	*http.Server
 	output     io.Writer
 	store      Storage
+	hub        *hub
 	fileServer http.Handler
 }
 
@@ -259,9 +1032,25 @@ var resources embed.FS
 //go:embed static *.css
 var css embed.FS
 
+// openAPISpec documents every route in Routes. Keep it in step with the
+// router, a test fails when a route has no entry.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
 var tmpl = template.Must(template.ParseFS(resources, templatesDir))
 
+// render writes data with the template, or as JSON when the client asks for
+// it in the Accept header.
 func render(w http.ResponseWriter, r *http.Request, templateName string, data any) {
+	if acceptsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
 	err := tmpl.ExecuteTemplate(w, templateName, data)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -275,6 +1064,30 @@ const (
 	formMatchTemplate  = "matchForm.html"
 	matchTemplate      = "match.html"
 	matchTableTemplate = "matchTable.html"
+	handFormTemplate   = "handForm.html"
+	watchTemplate      = "watch.html"
+	signupTemplate     = "signup.html"
+	loginTemplate      = "login.html"
+	watchTableTemplate = "watchTable.html"
+
+	tournamentsTemplate    = "tournaments.html"
+	tournamentTemplate     = "tournament.html"
+	tournamentFormTemplate = "tournamentForm.html"
+	seriesTemplate         = "series.html"
+	seriesFormTemplate     = "seriesForm.html"
+	leaguesTemplate        = "leagues.html"
+	leagueTemplate         = "league.html"
+	leagueFormTemplate     = "leagueForm.html"
+	ratingsTemplate        = "ratings.html"
+	ratingHistoryTemplate  = "ratingHistory.html"
+	statsTemplate          = "stats.html"
+	headToHeadTemplate     = "h2h.html"
+
+	matchesTemplate       = "matches.html"
+	playersTemplate       = "players.html"
+	playerOptionsTemplate = "playerOptions.html"
+
+	dateLayout = "2006-01-02"
 
 	dbVolume = "SQLITE_VOLUME"
 
@@ -10,7 +10,6 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
-	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -166,7 +165,7 @@ func TestMatchHandlerCreatesMatch(t *testing.T) {
 	if len(location) < 2 {
 		t.Errorf("want url to contain ID")
 	}
-	id, err := strconv.ParseInt(location[2], 10, 64)
+	id, err := store.GetMatchIDBySlug(location[2])
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -199,9 +198,10 @@ func TestMatchHandlerRendersMatchScore(t *testing.T) {
 	}
 
 	rec := httptest.NewRecorder()
-	url := fmt.Sprintf("/match/%d", m.Id)
+	url := "/match/" + m.Slug
 	req := httptest.NewRequest(http.MethodGet, url, nil)
-	req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(m.Id, 10)})
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
 
 	handler := server.HandleMatch()
 	handler(rec, req)
@@ -241,10 +241,11 @@ func TestMatchHandlerUpdatesScore(t *testing.T) {
 	}
 
 	rec := httptest.NewRecorder()
-	url := fmt.Sprintf("/match/%d", m.Id)
+	url := "/match/" + m.Slug
 	form := strings.NewReader("team1_points=20&team2_points=0")
 	req := httptest.NewRequest(http.MethodPatch, url, form)
-	req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(m.Id, 10)})
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	handler := server.HandleMatch()
@@ -298,6 +299,18 @@ func TestStaticFilesAreBeingServed(t *testing.T) {
 	if !strings.Contains(string(body), want) {
 		t.Errorf("expected stylesheet to contain tailwindcss")
 	}
+
+	res, err = http.Get(testServer.URL + "/static/sse.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || !strings.Contains(string(body), "sse-connect") {
+		t.Errorf("want the sse extension served, got %d\n%s", res.StatusCode, body)
+	}
 }
 
 // Run server tests
@@ -353,3 +366,922 @@ func TestRunServerSetsDbOnDifferentLocation(t *testing.T) {
 		t.Error("want to find db in non-default location")
 	}
 }
+
+func TestMatchHandlerRendersHandHistory(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch(dominocount.MatchWithTeam1Name("foo"), dominocount.MatchWithTeam2Name("bar"))
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 37, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 0, 58)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	url := "/match/" + m.Slug
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+
+	handler := server.HandleMatch()
+	handler(rec, req)
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200 OK, got %d", res.StatusCode)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(body)
+	for _, want := range []string{"37", "58"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want match history to contain %s\nGot:\n%s", want, got)
+		}
+	}
+}
+
+func TestMatchHandlerRendersScoreChart(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch(dominocount.MatchWithTeam1Name("foo"), dominocount.MatchWithTeam2Name("bar"))
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 37, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 0, 58)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/match/"+m.Slug, nil)
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	server.Routes().ServeHTTP(rec, req)
+
+	got := rec.Body.String()
+	for _, want := range []string{
+		`points="0,200.0 300.0,163.0 600.0,163.0"`,
+		`points="0,200.0 300.0,200.0 600.0,142.0"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want the score chart to contain %s\nGot:\n%s", want, got)
+		}
+	}
+}
+
+func TestUndoHandlerRemovesLastHand(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 85, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	url := "/match/" + m.Slug + "/undo"
+	req := httptest.NewRequest(http.MethodPost, url, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+
+	handler := server.HandleUndo()
+	handler(rec, req)
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200 OK, got %d", res.StatusCode)
+	}
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Score1 != 0 || len(got.Hands) != 0 {
+		t.Errorf("want hand to be undone, got score %d and %d hands", got.Score1, len(got.Hands))
+	}
+}
+
+func TestHandHandlerCorrectsHand(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 85, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	url := "/match/" + m.Slug + "/hands/1"
+	form := strings.NewReader("team1_points=58&team2_points=0")
+	req := httptest.NewRequest(http.MethodPatch, url, form)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug, "number": "1"})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	handler := server.HandleHand()
+	handler(rec, req)
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200 OK, got %d", res.StatusCode)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "58") {
+		t.Errorf("want corrected table to contain 58\nGot:\n%s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	form = strings.NewReader("team1_points=-5&team2_points=0")
+	req = httptest.NewRequest(http.MethodPatch, url, form)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug, "number": "1"})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler(rec, req)
+
+	if rec.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("want status 400 for negative points, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestHandHandlerEditsBonuses(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddHandByID(m.Id, dominocount.NewHand(30, 0).WithBonus(dominocount.Team1, dominocount.BonusCapicua))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := server.HandleHand()
+
+	rec := httptest.NewRecorder()
+	url := "/match/" + m.Slug + "/hands/1"
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug, "number": "1"})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	handler(rec, req)
+
+	body, err := io.ReadAll(rec.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(body)
+	if !strings.Contains(got, `name="team1_points" value="30"`) {
+		t.Errorf("want the form to show the points without the bonus\nGot:\n%s", got)
+	}
+	if !strings.Contains(got, `name="team1_bonus" value="capicua" checked`) {
+		t.Errorf("want the capicua checked for team 1\nGot:\n%s", got)
+	}
+
+	rec = httptest.NewRecorder()
+	form := strings.NewReader("team1_points=30&team2_points=0")
+	req = httptest.NewRequest(http.MethodPatch, url, form)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug, "number": "1"})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler(rec, req)
+
+	if rec.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rec.Result().StatusCode)
+	}
+	hands, err := store.ListHands(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hands[0].Points1 != 30 || len(hands[0].Bonuses) != 0 {
+		t.Errorf("want the capicua dropped by the edit, got %+v", hands[0])
+	}
+}
+
+func TestMatchHandlerCreatesMatchWithTargetScore(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".store"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	form := strings.NewReader("team1_name=foo&team2_name=bar&target_score=100")
+	req := httptest.NewRequest(http.MethodPost, "/match/", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler := server.HandleMatch()
+	handler(rec, req)
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusSeeOther {
+		t.Fatalf("expected status 303 SeeOther, got %d", res.StatusCode)
+	}
+	location := strings.Split(res.Header.Get("location"), "/")
+	id, err := store.GetMatchIDBySlug(location[len(location)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := store.GetMatchByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Target != 100 {
+		t.Errorf("want target score 100, got %d", m.Target)
+	}
+}
+
+func TestMatchHandlerRejectsNegativeTargetScore(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".store"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	form := strings.NewReader("team1_name=foo&team2_name=bar&target_score=-5")
+	req := httptest.NewRequest(http.MethodPost, "/match/", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler := server.HandleMatch()
+	handler(rec, req)
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 BadRequest, got %d", res.StatusCode)
+	}
+}
+
+func TestMatchHandlerRendersWinnerWithoutPointsForm(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch(dominocount.MatchWithTeam1Name("foo"), dominocount.MatchWithTeam2Name("bar"))
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 0, 201)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	url := "/match/" + m.Slug
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+
+	handler := server.HandleMatch()
+	handler(rec, req)
+
+	body, err := io.ReadAll(rec.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(body)
+	if !strings.Contains(got, "ganador: bar") {
+		t.Errorf("want winner banner for bar\nGot:\n%s", got)
+	}
+	if strings.Contains(got, "sumar puntos") {
+		t.Errorf("want no points form once the match is over\nGot:\n%s", got)
+	}
+}
+
+func TestMatchHandlerRejectsPointsAfterGameOver(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 200, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	url := "/match/" + m.Slug
+	form := strings.NewReader("team1_points=20&team2_points=0")
+	req := httptest.NewRequest(http.MethodPatch, url, form)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	handler := server.HandleMatch()
+	handler(rec, req)
+
+	if rec.Result().StatusCode != http.StatusConflict {
+		t.Errorf("want status 409 Conflict, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestMatchHandlerCreatesMatchWithRuleSet(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".store"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	form := strings.NewReader("team1_name=foo&team2_name=bar&rule_set=cubano")
+	req := httptest.NewRequest(http.MethodPost, "/match/", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler := server.HandleMatch()
+	handler(rec, req)
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusSeeOther {
+		t.Fatalf("expected status 303 SeeOther, got %d", res.StatusCode)
+	}
+	location := strings.Split(res.Header.Get("location"), "/")
+	id, err := store.GetMatchIDBySlug(location[len(location)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := store.GetMatchByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Rules.Name() != "cubano" || m.Target != 100 {
+		t.Errorf("want cubano match to 100, got %s to %d", m.Rules.Name(), m.Target)
+	}
+}
+
+func TestMatchHandlerAddsTickedBonuses(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	url := "/match/" + m.Slug
+	form := strings.NewReader("team1_points=20&team2_points=0&team1_bonus=capicua&team1_bonus=salida")
+	req := httptest.NewRequest(http.MethodPatch, url, form)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	handler := server.HandleMatch()
+	handler(rec, req)
+
+	if rec.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rec.Result().StatusCode)
+	}
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Score1 != 70 {
+		t.Errorf("want 20 points plus two 25 point bonuses, got %d", got.Score1)
+	}
+
+	rec = httptest.NewRecorder()
+	form = strings.NewReader("team1_points=20&team2_points=0&team1_bonus=chiva")
+	req = httptest.NewRequest(http.MethodPatch, url, form)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler(rec, req)
+
+	if rec.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("want unknown bonus to be rejected with 400, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestMatchHandlerSettlesTranque(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	url := "/match/" + m.Slug
+	form := strings.NewReader("mode=tranque&team1_pips=4&team1_pips=6&team2_pips=9&team2_pips=11")
+	req := httptest.NewRequest(http.MethodPatch, url, form)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	handler := server.HandleMatch()
+	handler(rec, req)
+
+	if rec.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rec.Result().StatusCode)
+	}
+	hands, err := store.ListHands(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hands) != 1 || !hands[0].Tranque || hands[0].Points1 != 30 {
+		t.Errorf("want a tranque hand giving team 1 30 points, got %+v", hands)
+	}
+}
+
+func TestMatchHandlerCountsPickedTiles(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := server.HandleMatch()
+	url := "/match/" + m.Slug
+
+	rec := httptest.NewRecorder()
+	form := strings.NewReader("mode=tiles&winner=Team2&tiles=6-6&tiles=4-5&tiles=0-2")
+	req := httptest.NewRequest(http.MethodPatch, url, form)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler(rec, req)
+
+	if rec.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rec.Result().StatusCode)
+	}
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Score2 != 23 {
+		t.Errorf("want team 2 to get the 23 pips left, got %d", got.Score2)
+	}
+
+	rec = httptest.NewRecorder()
+	form = strings.NewReader("mode=tiles&winner=Team2&tiles=6-6&tiles=6-6")
+	req = httptest.NewRequest(http.MethodPatch, url, form)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler(rec, req)
+
+	if rec.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("want repeated tile to be rejected with 400, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestMatchHandlerCreatesMatchWithPlayers(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".store"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	form := strings.NewReader("team1_name=foo&team2_name=bar&north_name=Ana&south_name=Juan&east_name=Luis&west_name=Rosa")
+	req := httptest.NewRequest(http.MethodPost, "/match/", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler := server.HandleMatch()
+	handler(rec, req)
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusSeeOther {
+		t.Fatalf("expected status 303 SeeOther, got %d", res.StatusCode)
+	}
+	location := strings.Split(res.Header.Get("location"), "/")
+	slug := location[len(location)-1]
+
+	rec = httptest.NewRecorder()
+	points := strings.NewReader("team1_points=0&team2_points=25&domino=east")
+	req = httptest.NewRequest(http.MethodPatch, "/match/"+slug, points)
+	req = mux.SetURLVars(req, map[string]string{"id": slug})
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range res.Cookies() {
+		req.AddCookie(cookie)
+	}
+	handler(rec, req)
+
+	body, err := io.ReadAll(rec.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "dominó Luis") {
+		t.Errorf("want hand history to show Luis won the hand\nGot:\n%s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	form = strings.NewReader("team1_name=foo&team2_name=bar&north_name=Ana&east_name=ana")
+	req = httptest.NewRequest(http.MethodPost, "/match/", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler(rec, req)
+
+	if rec.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("want status 400 seating Ana twice, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestPlayersHandlerRendersAutocompleteOptions(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Rosa", "Luis"} {
+		_, err = store.CreatePlayer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/players?q=ro", nil)
+	req.Header.Set("HX-Request", "true")
+
+	handler := server.HandlePlayers()
+	handler(rec, req)
+
+	if rec.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rec.Result().StatusCode)
+	}
+	body, err := io.ReadAll(rec.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(body)
+	if !strings.Contains(got, `<option value="Rosa">`) {
+		t.Errorf("want Rosa as an option\nGot:\n%s", got)
+	}
+	if strings.Contains(got, "Luis") {
+		t.Errorf("want Luis to be filtered out\nGot:\n%s", got)
+	}
+}
+
+func TestMatchesHandlerListsMatches(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo", "bar"} {
+		m := dominocount.NewMatch(dominocount.MatchWithTeam1Name(name))
+		err = store.CreateMatch(&m)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/matches?team=foo", nil)
+	handler := server.HandleMatches()
+	handler(rec, req)
+
+	if rec.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rec.Result().StatusCode)
+	}
+	body, err := io.ReadAll(rec.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(body)
+	if !strings.Contains(got, "foo - Team2") {
+		t.Errorf("want listing to contain the foo match\nGot:\n%s", got)
+	}
+	if strings.Contains(got, "bar - Team2") {
+		t.Errorf("want bar match to be filtered out\nGot:\n%s", got)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/matches?from=yesterday", nil)
+	handler(rec, req)
+	if rec.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("want bad date to be rejected with 400, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestMatchHandlerDeleteArchivesMatch(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/match/"+m.Slug, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	server.HandleMatch()(rec, req)
+
+	if rec.Result().StatusCode != http.StatusNoContent {
+		t.Fatalf("expected status 204 No Content, got %d", rec.Result().StatusCode)
+	}
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Archived() {
+		t.Error("want deleted match to be archived")
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/match/"+m.Slug+"/restore", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	server.HandleRestore()(rec, req)
+
+	if rec.Result().StatusCode != http.StatusSeeOther {
+		t.Fatalf("expected status 303 SeeOther, got %d", rec.Result().StatusCode)
+	}
+	got, err = store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Archived() {
+		t.Error("want match to be restored")
+	}
+}
+
+func TestMatchHandlerRejectsHandChangesOnArchivedMatch(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.DeleteMatch(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/match/"+m.Slug+"/undo", nil),
+		httptest.NewRequest(http.MethodPatch, "/match/"+m.Slug+"/hands/1", strings.NewReader("team1_points=0&team2_points=20")),
+	} {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Edit-Secret", m.EditSecret)
+		rec := httptest.NewRecorder()
+		server.Routes().ServeHTTP(rec, req)
+		if rec.Code != http.StatusConflict {
+			t.Errorf("want status 409 for %s %s on an archived match, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+}
+
+func TestMatchHandlerRequiresEditSecret(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/match/"+m.Slug, strings.NewReader("team1_points=20&team2_points=0"))
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	server.HandleMatch()(rec, req)
+	if rec.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("want status 403 adding points without the edit secret, got %d", rec.Result().StatusCode)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodDelete, "/match/"+m.Slug, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.AddCookie(&http.Cookie{Name: "edit_" + m.Slug, Value: "nope"})
+	server.HandleMatch()(rec, req)
+	if rec.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("want status 403 deleting with a wrong edit secret, got %d", rec.Result().StatusCode)
+	}
+
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Score1 != 0 || got.Archived() {
+		t.Errorf("want match untouched by strangers, got %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPatch, "/match/"+m.Slug, strings.NewReader("team1_points=20&team2_points=0"))
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "edit_" + m.Slug, Value: m.EditSecret})
+	server.HandleMatch()(rec, req)
+	if rec.Result().StatusCode != http.StatusOK {
+		t.Errorf("want status 200 OK adding points with the edit cookie, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestMatchHandlerSendsVisitorsWithoutSecretToSpectatorView(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/match/"+m.Slug, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	server.HandleMatch()(rec, req)
+	res := rec.Result()
+	if res.StatusCode != http.StatusSeeOther || res.Header.Get("Location") != "/watch/"+m.WatchToken {
+		t.Errorf("want redirect to the spectator view, got %d to %s", res.StatusCode, res.Header.Get("Location"))
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/match/"+m.Slug+"?clave="+m.EditSecret, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	server.HandleMatch()(rec, req)
+	res = rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("want status 200 OK with the edit secret, got %d", res.StatusCode)
+	}
+	cookies := res.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "edit_"+m.Slug || cookies[0].Value != m.EditSecret || !cookies[0].HttpOnly {
+		t.Errorf("want the edit secret remembered in an HttpOnly cookie, got %+v", cookies)
+	}
+}
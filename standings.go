@@ -0,0 +1,255 @@
+package dominocount
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// leagueForm is the data of the create league form.
+type leagueForm struct {
+	Name                                 string
+	Teams                                string
+	WinPoints, LossPoints, ShutoutPoints int
+	SeasonName, SeasonStart, SeasonEnd   string
+	Error                                string
+}
+
+func newLeagueForm() leagueForm {
+	return leagueForm{
+		WinPoints:     DefaultWinPoints,
+		LossPoints:    DefaultLossPoints,
+		ShutoutPoints: DefaultShutoutPoints,
+		SeasonName:    "Temporada 1",
+		SeasonStart:   time.Now().UTC().Format(dateLayout),
+	}
+}
+
+// leaguePage is the data of the league page, the standings of one season.
+type leaguePage struct {
+	*league
+	Season    *season          `json:"season"`
+	Standings []leagueStanding `json:"standings"`
+	Order     string           `json:"-"`
+	Editable  bool             `json:"-"`
+	Error     string           `json:"-"`
+}
+
+// SortURL returns the link sorting the standings of the season by column.
+func (p leaguePage) SortURL(column string) string {
+	query := url.Values{"orden": {column}}
+	if p.Season != nil {
+		query.Set("temporada", strconv.FormatInt(p.Season.ID, 10))
+	}
+	return "/leagues/" + p.Slug + "?" + query.Encode()
+}
+
+// HandleLeagues lists the leagues on GET and creates a league on POST.
+func (s server) HandleLeagues() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			leagues, err := s.store.ListLeagues()
+			if err != nil {
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			render(w, r, leaguesTemplate, leagues)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			s.handleCreateLeague(w, r)
+			return
+		}
+
+		http.Error(w, "method not supported", http.StatusBadRequest)
+	}
+}
+
+func (s server) HandleLeagueForm() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render(w, r, leagueFormTemplate, newLeagueForm())
+	}
+}
+
+// HandleLeague renders the standings of a season of the league. The season
+// is chosen with temporada in the query, the latest by default, and the
+// table is sorted by the column in orden.
+func (s server) HandleLeague() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		l, ok := s.league(w, r)
+		if !ok {
+			return
+		}
+		page := leaguePage{league: l, Order: r.URL.Query().Get("orden")}
+		page.Editable = s.store.CheckLeagueEditAccess(l.ID, editSecret(r), currentUserID(r)) == nil
+		if page.Editable && r.URL.Query().Get("clave") != "" {
+			setEditCookie(w, l.Slug, editSecret(r))
+		}
+		s.renderLeague(w, r, page, r.URL.Query().Get("temporada"))
+	}
+}
+
+// HandleSeasons opens a new season of the league, only for its editors.
+func (s server) HandleSeasons() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		l, ok := s.league(w, r)
+		if !ok {
+			return
+		}
+		err := s.store.CheckLeagueEditAccess(l.ID, editSecret(r), currentUserID(r))
+		if err != nil {
+			http.Error(w, "not allowed to edit this league", http.StatusForbidden)
+			return
+		}
+
+		sn, err := formParseSeason(r)
+		if err == nil {
+			err = s.store.AddSeason(l.ID, &sn)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			page := leaguePage{league: l, Editable: true, Error: "La temporada necesita un nombre y un inicio antes del final."}
+			s.renderLeague(w, r, page, "")
+			return
+		}
+		http.Redirect(w, r, "/leagues/"+l.Slug+"?temporada="+strconv.FormatInt(sn.ID, 10), http.StatusSeeOther)
+	}
+}
+
+// handleCreateLeague registers the teams sent one per line with the first
+// season and redirects to the league. The browser keeps the edit secret like
+// for a match.
+func (s server) handleCreateLeague(w http.ResponseWriter, r *http.Request) {
+	form := newLeagueForm()
+	form.Name = r.PostFormValue("name")
+	form.Teams = r.PostFormValue("teams")
+	form.SeasonName = r.PostFormValue("season_name")
+	form.SeasonStart = r.PostFormValue("season_start")
+	form.SeasonEnd = r.PostFormValue("season_end")
+
+	var err error
+	form.WinPoints, err = formParseLeaguePoints(r, "win_points", form.WinPoints)
+	if err == nil {
+		form.LossPoints, err = formParseLeaguePoints(r, "loss_points", form.LossPoints)
+	}
+	if err == nil {
+		form.ShutoutPoints, err = formParseLeaguePoints(r, "shutout_points", form.ShutoutPoints)
+	}
+	var sn season
+	if err == nil {
+		sn, err = formParseSeason(r)
+	}
+	if err != nil {
+		form.Error = "Los puntos deben ser números y las fechas válidas."
+		w.WriteHeader(http.StatusBadRequest)
+		render(w, r, leagueFormTemplate, form)
+		return
+	}
+
+	l := NewLeague(
+		LeagueWithName(form.Name),
+		LeagueWithTeams(strings.Split(form.Teams, "\n")...),
+		LeagueWithPoints(form.WinPoints, form.LossPoints, form.ShutoutPoints),
+		LeagueWithOwner(currentUserID(r)),
+	)
+	l.Seasons = append(l.Seasons, sn)
+	err = s.store.CreateLeague(&l)
+	if err != nil {
+		form.Error = "Hace falta un nombre, al menos dos equipos distintos y una temporada."
+		w.WriteHeader(http.StatusBadRequest)
+		render(w, r, leagueFormTemplate, form)
+		return
+	}
+	setEditCookie(w, l.Slug, l.EditSecret)
+	http.Redirect(w, r, "/leagues/"+l.Slug, http.StatusSeeOther)
+}
+
+// league loads the league of the request, on failure the error has been
+// written and ok is false.
+func (s server) league(w http.ResponseWriter, r *http.Request) (l *league, ok bool) {
+	l, err := s.store.GetLeagueBySlug(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if l.ID == 0 {
+		http.Error(w, "league not found", http.StatusNotFound)
+		return nil, false
+	}
+	return l, true
+}
+
+// renderLeague fills in the standings of the season with the ID in seasonID,
+// the latest season when empty.
+func (s server) renderLeague(w http.ResponseWriter, r *http.Request, page leaguePage, seasonID string) {
+	if seasonID != "" {
+		for i, sn := range page.Seasons {
+			if strconv.FormatInt(sn.ID, 10) == seasonID {
+				page.Season = &page.Seasons[i]
+			}
+		}
+		if page.Season == nil {
+			http.Error(w, "season not found", http.StatusNotFound)
+			return
+		}
+	} else if len(page.Seasons) > 0 {
+		page.Season = &page.Seasons[0]
+	}
+
+	if page.Season != nil {
+		standings, err := s.store.LeagueStandings(page.ID, page.Season.ID)
+		if err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		sortStandings(standings, page.Order)
+		page.Standings = standings
+	}
+	render(w, r, leagueTemplate, page)
+}
+
+// formParseSeason reads a season from the season fields of a form, dates use
+// the date input format and the end is optional.
+func formParseSeason(r *http.Request) (season, error) {
+	start, err := time.Parse(dateLayout, r.PostFormValue("season_start"))
+	if err != nil {
+		return season{}, errors.New("not able to parse season start")
+	}
+	var end time.Time
+	if value := r.PostFormValue("season_end"); value != "" {
+		end, err = time.Parse(dateLayout, value)
+		if err != nil {
+			return season{}, errors.New("not able to parse season end")
+		}
+	}
+	sn := NewSeason(r.PostFormValue("season_name"), start, end)
+	return sn, sn.validate()
+}
+
+// formParseLeaguePoints reads the league points in the field, fallback when
+// the field is empty.
+func formParseLeaguePoints(r *http.Request, field string, fallback int) (int, error) {
+	value := r.PostFormValue(field)
+	if value == "" {
+		return fallback, nil
+	}
+	points, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("not able to parse " + field)
+	}
+	return points, nil
+}
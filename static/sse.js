@@ -0,0 +1,25 @@
+// sse is the part of the htmx sse extension the match pages use, served from
+// here instead of a CDN. An element with sse-connect listens to that URL and
+// sse-swap names the event whose data replaces its content.
+htmx.defineExtension("sse", {
+    onEvent: function (name, evt) {
+        if (name !== "htmx:afterProcessNode") {
+            return;
+        }
+        var elt = evt.detail.elt;
+        var url = elt.getAttribute && elt.getAttribute("sse-connect");
+        if (!url || elt.sseSource) {
+            return;
+        }
+        var source = new EventSource(url);
+        elt.sseSource = source;
+        source.addEventListener(elt.getAttribute("sse-swap"), function (e) {
+            if (!document.body.contains(elt)) {
+                source.close();
+                return;
+            }
+            elt.innerHTML = e.data;
+            htmx.process(elt);
+        });
+    }
+});
@@ -0,0 +1,203 @@
+package dominocount
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// statsResult is a finished match seen from the side of a player or team.
+type statsResult struct {
+	MatchSlug  string    `json:"match"`
+	Opponent   string    `json:"opponent"`
+	FinishedAt time.Time `json:"finished_at"`
+	Won        bool      `json:"won"`
+	For        int       `json:"points_for"`
+	Against    int       `json:"points_against"`
+	Hands      int       `json:"hands"`
+	// Comeback is the largest deficit overcome to win, 0 in a loss.
+	Comeback int `json:"comeback"`
+}
+
+// newStatsResult replays the hands of m from the side of t.
+func newStatsResult(m match, t team) statsResult {
+	r := statsResult{
+		MatchSlug:  m.Slug,
+		Opponent:   m.Team2,
+		FinishedAt: m.FinishedAt,
+		Won:        m.Winner() == t,
+		For:        m.Score(t),
+		Hands:      len(m.Hands),
+	}
+	other := Team2
+	if t == Team2 {
+		other, r.Opponent = Team1, m.Team1
+	}
+	r.Against = m.Score(other)
+	if !r.Won {
+		return r
+	}
+	own, rival := 0, 0
+	for _, h := range m.Hands {
+		if t == Team1 {
+			own, rival = own+h.Points1, rival+h.Points2
+		} else {
+			own, rival = own+h.Points2, rival+h.Points1
+		}
+		if rival-own > r.Comeback {
+			r.Comeback = rival - own
+		}
+	}
+	return r
+}
+
+// stats sums up the finished matches of a player or a team, oldest first.
+type stats struct {
+	Name    string        `json:"name"`
+	Results []statsResult `json:"results"`
+}
+
+// Played returns the finished matches.
+func (s stats) Played() int {
+	return len(s.Results)
+}
+
+// Won returns the matches won.
+func (s stats) Won() int {
+	won := 0
+	for _, r := range s.Results {
+		if r.Won {
+			won++
+		}
+	}
+	return won
+}
+
+// WinRate returns the percentage of matches won, 0 without matches.
+func (s stats) WinRate() float64 {
+	if s.Played() == 0 {
+		return 0
+	}
+	return 100 * float64(s.Won()) / float64(s.Played())
+}
+
+// AveragePointsPerHand returns the points scored per hand played.
+func (s stats) AveragePointsPerHand() float64 {
+	points, hands := 0, 0
+	for _, r := range s.Results {
+		points += r.For
+		hands += r.Hands
+	}
+	if hands == 0 {
+		return 0
+	}
+	return float64(points) / float64(hands)
+}
+
+// AverageHandsToFinish returns how many hands a match lasts.
+func (s stats) AverageHandsToFinish() float64 {
+	if s.Played() == 0 {
+		return 0
+	}
+	hands := 0
+	for _, r := range s.Results {
+		hands += r.Hands
+	}
+	return float64(hands) / float64(s.Played())
+}
+
+// BiggestComeback returns the win where the largest deficit was overcome, nil
+// when no win had to come back.
+func (s stats) BiggestComeback() *statsResult {
+	var best *statsResult
+	for i, r := range s.Results {
+		if r.Comeback > 0 && (best == nil || r.Comeback > best.Comeback) {
+			best = &s.Results[i]
+		}
+	}
+	return best
+}
+
+// LongestStreak returns the most matches won in a row.
+func (s stats) LongestStreak() int {
+	longest, current := 0, 0
+	for _, r := range s.Results {
+		if !r.Won {
+			current = 0
+			continue
+		}
+		current++
+		if current > longest {
+			longest = current
+		}
+	}
+	return longest
+}
+
+// Shutouts returns the chivos, wins where the rivals did not score.
+func (s stats) Shutouts() int {
+	shutouts := 0
+	for _, r := range s.Results {
+		if r.Won && r.Against == 0 {
+			shutouts++
+		}
+	}
+	return shutouts
+}
+
+// MarginChart draws the point difference of the latest matches, wins above
+// the axis and losses below.
+func (s stats) MarginChart() chart {
+	c := chart{Width: chartWidth, Height: chartHeight, Axis: chartHeight / 2}
+	results := s.Results
+	if len(results) > chartMatches {
+		results = results[len(results)-chartMatches:]
+	}
+	largest := 1
+	for _, r := range results {
+		if margin := abs(r.For - r.Against); margin > largest {
+			largest = margin
+		}
+	}
+	slot := float64(chartWidth) / float64(chartMatches)
+	for i, r := range results {
+		height := float64(abs(r.For-r.Against)) / float64(largest) * (c.Axis - 4)
+		bar := chartBar{
+			X:      float64(i)*slot + 2,
+			Y:      c.Axis,
+			Width:  slot - 4,
+			Height: height,
+			Won:    r.Won,
+			Title:  fmt.Sprintf("%d - %d contra %s", r.For, r.Against, r.Opponent),
+		}
+		if r.Won {
+			bar.Y -= height
+		}
+		c.Bars = append(c.Bars, bar)
+	}
+	return c
+}
+
+// WinRateChart draws the win rate after each match, from 0% at the bottom to
+// 100% at the top.
+func (s stats) WinRateChart() chart {
+	c := chart{Width: chartWidth, Height: chartHeight, Axis: chartHeight / 2}
+	if len(s.Results) == 0 {
+		return c
+	}
+	points := make([]string, 0, len(s.Results))
+	step := float64(chartWidth)
+	if len(s.Results) > 1 {
+		step = float64(chartWidth) / float64(len(s.Results)-1)
+	}
+	won := 0
+	for i, r := range s.Results {
+		if r.Won {
+			won++
+		}
+		rate := float64(won) / float64(i+1)
+		points = append(points, fmt.Sprintf("%.1f,%.1f", float64(i)*step, (1-rate)*chartHeight))
+	}
+	c.Lines = []chartLine{{Name: "victorias", Points: strings.Join(points, " ")}}
+	return c
+}
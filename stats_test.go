@@ -0,0 +1,127 @@
+package dominocount_test
+
+import (
+	"dominocount"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSQLiteStore_PlayerAndTeamStats(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	play := func(north, south, east, west string, hands ...[2]int) {
+		t.Helper()
+		m := dominocount.NewMatch(
+			dominocount.MatchWithTeam1Name("Los Primos"),
+			dominocount.MatchWithTeam2Name("La Esquina"),
+			dominocount.MatchWithTargetScore(100),
+			dominocount.MatchWithPlayer(dominocount.SeatNorth, north),
+			dominocount.MatchWithPlayer(dominocount.SeatSouth, south),
+			dominocount.MatchWithPlayer(dominocount.SeatEast, east),
+			dominocount.MatchWithPlayer(dominocount.SeatWest, west),
+		)
+		err := store.CreateMatch(&m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range hands {
+			_, err = store.AddHandByID(m.Id, dominocount.NewHand(h[0], h[1]))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	play("Ana", "Beto", "Cara", "Dani", [2]int{0, 30}, [2]int{50, 0}, [2]int{60, 0})
+	play("Ana", "Beto", "Cara", "Dani", [2]int{100, 0})
+	play("Cara", "Dani", "Ana", "Beto", [2]int{100, 0})
+	play("Ana", "Beto", "Cara", "Dani", [2]int{40, 0})
+
+	players, err := store.ListPlayers("Ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ana int64
+	for _, p := range players {
+		if p.Name == "Ana" {
+			ana = p.ID
+		}
+	}
+	st, err := store.PlayerStats(ana)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Played() != 3 || st.Won() != 2 || st.LongestStreak() != 2 || st.Shutouts() != 1 {
+		t.Errorf("want 2 of 3 won in a row with a chivo, got %d of %d, streak %d, %d chivos", st.Won(), st.Played(), st.LongestStreak(), st.Shutouts())
+	}
+	if got := st.AveragePointsPerHand(); got != 42 {
+		t.Errorf("want 42 points per hand, got %f", got)
+	}
+	if c := st.BiggestComeback(); c == nil || c.Comeback != 30 || c.Opponent != "La Esquina" {
+		t.Errorf("want a comeback of 30 against La Esquina, got %+v", c)
+	}
+
+	st, err = store.TeamStats("la esquina")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Played() != 3 || st.Won() != 0 || st.AverageHandsToFinish() != 5.0/3 {
+		t.Errorf("want La Esquina to lose 3 matches of 5/3 hands, got %+v", st)
+	}
+
+	_, err = store.PlayerStats(999)
+	if _, ok := err.(*dominocount.PlayerNotFoundError); !ok {
+		t.Errorf("want PlayerNotFoundError for an unknown player, got %v", err)
+	}
+}
+
+func TestStatsPagesDrawSVGCharts(t *testing.T) {
+	t.Parallel()
+	client, testServer, store := newAccountTestClient(t)
+	m := dominocount.NewMatch(
+		dominocount.MatchWithTeam1Name("Los Primos"),
+		dominocount.MatchWithTeam2Name("La Esquina"),
+	)
+	err := store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 200, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get(testServer.URL + "/stats/teams/" + url.PathEscape("los primos"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(body)
+	if res.StatusCode != http.StatusOK || !strings.Contains(got, "<svg") || !strings.Contains(got, "<rect") || !strings.Contains(got, "<polyline") {
+		t.Errorf("want the team stats with SVG charts, got %d\n%s", res.StatusCode, got)
+	}
+
+	res, err = client.Get(testServer.URL + "/stats/teams/Nadie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("want status 404 for a team without matches, got %d", res.StatusCode)
+	}
+	res, err = client.Get(testServer.URL + "/stats/players/999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("want status 404 for an unknown player, got %d", res.StatusCode)
+	}
+}
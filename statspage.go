@@ -0,0 +1,52 @@
+package dominocount
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// HandlePlayerStats renders the statistics of a registered player.
+func (s server) HandlePlayerStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		id, err := queryStringParseID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		st, err := s.store.PlayerStats(id)
+		if _, ok := err.(*PlayerNotFoundError); ok {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		render(w, r, statsTemplate, st)
+	}
+}
+
+// HandleTeamStats renders the statistics of a team, by its name in the path.
+func (s server) HandleTeamStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		st, err := s.store.TeamStats(mux.Vars(r)["name"])
+		if err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		if st.Played() == 0 {
+			http.Error(w, "team has no finished matches", http.StatusNotFound)
+			return
+		}
+		render(w, r, statsTemplate, st)
+	}
+}
@@ -1,17 +1,66 @@
 package dominocount
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 	_ "modernc.org/sqlite"
 )
 
 type Storage interface {
 	CreateMatch(*match) error
-	//DeleteMatch(int)error
+	DeleteMatch(int64) error
+	RestoreMatch(int64) error
 	UpdateMatch(*match) error
 	GetMatchByID(int64) (*match, error)
+	GetMatchByWatchToken(string) (*match, error)
+	GetMatchIDBySlug(string) (int64, error)
+	CheckEditAccess(int64, string, int64) error
+	CreateUser(string, string) (*user, error)
+	AuthenticateUser(string, string) (*user, error)
+	CreateSession(int64) (string, error)
+	GetUserBySession(string) (*user, error)
+	DeleteSession(string) error
+	CreateTournament(*tournament) error
+	GetTournamentBySlug(string) (*tournament, error)
+	ListTournaments() ([]tournament, error)
+	CheckTournamentEditAccess(int64, string, int64) error
+	CreateSeries(*series) error
+	GetSeriesBySlug(string) (*series, error)
+	CheckSeriesEditAccess(int64, string, int64) error
+	CreateLeague(*league) error
+	AddSeason(int64, *season) error
+	GetLeagueBySlug(string) (*league, error)
+	ListLeagues() ([]league, error)
+	CheckLeagueEditAccess(int64, string, int64) error
+	LeagueStandings(int64, int64) ([]leagueStanding, error)
+	RecomputeRatings() (int, error)
+	ListPlayerRatings() ([]playerRating, error)
+	ListPairRatings() ([]playerRating, error)
+	ListRatingHistory(int64) ([]ratingChange, error)
+	PlayerStats(int64) (*stats, error)
+	TeamStats(string) (*stats, error)
 	AddPointsByID(int64, int, int) (*match, error)
+	AddHandByID(int64, hand) (*match, error)
+	AbandonMatch(int64) (*match, error)
+	CreatePlayer(string) (*player, error)
+	ListPlayers(string) ([]player, error)
+	GetPlayerByID(int64) (*player, error)
+	RenamePlayer(int64, string) error
+	MergePlayers(int64, int64) error
+	ListMatches(MatchFilter) ([]match, int64, error)
+	AddHand(*hand) error
+	ListHands(int64) ([]hand, error)
+	UndoLastHand(int64) (*match, error)
+	UpdateHand(int64, int, hand) (*match, error)
 }
 
 func OpenSQLiteStore(dbPath string) (sqliteStore, error) {
@@ -19,19 +68,17 @@ func OpenSQLiteStore(dbPath string) (sqliteStore, error) {
 		return sqliteStore{}, errors.New("db path cannot be empty")
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := sql.Open("sqlite", dbPath+connectionParams)
 	if err != nil {
 		return sqliteStore{}, err
 	}
 
-	for _, stmt := range []string{pragmaWALEnabled, pragma500BusyTimeout, pragmaForeignKeysON} {
-		_, err = db.Exec(stmt, nil)
-		if err != nil {
-			return sqliteStore{}, err
-		}
+	_, err = db.Exec(pragmaWALEnabled, nil)
+	if err != nil {
+		return sqliteStore{}, err
 	}
 
-	_, err = db.Exec(createMatchTable)
+	err = migrate(db)
 	if err != nil {
 		return sqliteStore{}, err
 	}
@@ -40,13 +87,77 @@ func OpenSQLiteStore(dbPath string) (sqliteStore, error) {
 
 }
 
+// migrate applies the schema migrations the database has not seen yet. The
+// number of applied migrations is tracked in PRAGMA user_version.
+func migrate(db *sql.DB) error {
+	var version int
+	err := db.QueryRow(getUserVersion).Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(migrations[i])
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		_, err = tx.Exec(fmt.Sprintf(setUserVersion, i+1))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateMatch stores m and gives it its public slug, spectator token and edit
+// secret. Only the hash of the secret is stored, m.EditSecret is the one
+// chance to hand it to the scorekeeper.
 func (s *sqliteStore) CreateMatch(m *match) error {
-	stmt, err := s.db.Prepare(insertMatch)
+	editSecret, err := randomToken(16)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = createMatchTx(tx, m, hashToken(editSecret))
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	m.EditSecret = editSecret
+	return nil
+}
+
+// createMatchTx inserts m with its players, editable with the secret hashed
+// as editSecretHash.
+func createMatchTx(tx *sql.Tx, m *match, editSecretHash string) error {
+	slug, err := randomToken(slugBytes)
+	if err != nil {
+		return err
+	}
+	watchToken, err := randomToken(16)
 	if err != nil {
 		return err
 	}
 
-	rs, err := stmt.Exec(m.Team1, m.Team2)
+	rs, err := tx.Exec(insertMatch, m.Team1, m.Team2, m.Target, m.Rules.Name(), m.Status, m.CreatedAt, watchToken, slug, editSecretHash, nullID(m.OwnerID))
 	if err != nil {
 		return err
 	}
@@ -54,36 +165,412 @@ func (s *sqliteStore) CreateMatch(m *match) error {
 	if err != nil {
 		return err
 	}
+	seated := map[int64]bool{}
+	for i, p := range m.Players {
+		registered, err := playerByNameTx(tx, p.Name)
+		if err != nil {
+			return err
+		}
+		if seated[registered.ID] {
+			return &PlayersShareMatchError{}
+		}
+		seated[registered.ID] = true
+		_, err = tx.Exec(insertMatchPlayer, lastInsertID, p.Seat, registered.ID)
+		if err != nil {
+			return err
+		}
+		m.Players[i].ID = registered.ID
+		m.Players[i].Name = registered.Name
+	}
 	m.Id = lastInsertID
+	m.Slug = slug
+	m.WatchToken = watchToken
+	return nil
+}
+
+// slugBytes is the size of the random match slugs, long enough that matches
+// cannot be enumerated.
+const slugBytes = 8
+
+// randomToken returns size random bytes hex encoded, for the unguessable
+// slugs, spectator tokens and edit secrets of the matches.
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken is what gets stored of edit secrets and session tokens. They are
+// random, so a plain SHA-256 is enough to keep a leaked database from handing
+// out edit rights or logins.
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkEditAccess returns EditForbiddenError unless userID is ownerID or
+// secret hashes to hash. A zero userID is anonymous and owns nothing.
+func checkEditAccess(hash string, ownerID int64, secret string, userID int64) error {
+	if userID != 0 && ownerID == userID {
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashToken(secret))) != 1 {
+		return &EditForbiddenError{}
+	}
 	return nil
 }
 
 func (s *sqliteStore) UpdateMatch(m *match) error {
-	stmt, err := s.db.Prepare(updateMatch)
+	return execUpdateMatch(s.db, m)
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx so statements can be shared
+// between plain and transactional writes.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func execUpdateMatch(e execer, m *match) error {
+	_, err := e.Exec(updateMatch, m.Team1, m.Team2, m.Score1, m.Score2, m.Status, nullTime(m.FinishedAt), m.Id)
+	return err
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+func (s *sqliteStore) AddPointsByID(id int64, score1 int, score2 int) (*match, error) {
+	return s.AddHandByID(id, NewHand(score1, score2))
+}
+
+// AddHandByID scores h on the match and records it in the hand history.
+func (s *sqliteStore) AddHandByID(id int64, h hand) (*match, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	m, err := readMatch(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Archived() {
+		return nil, &MatchArchivedError{}
+	}
+	if !m.InProgress() {
+		return nil, &GameOverError{}
+	}
+	before := *m
+	played := m.PlayHand(h)
+
+	err = execUpdateMatch(tx, m)
+	if err != nil {
+		return nil, err
+	}
+	err = syncMatchTx(tx, before, *m)
+	if err != nil {
+		return nil, err
+	}
+	err = insertHandTx(tx, &played)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	m.Hands = append(m.Hands, played)
+	return m, nil
+}
+
+// AddHand appends h to the hand history of its match. The hand number and
+// timestamp are assigned by the store. Match totals are not modified.
+func (s *sqliteStore) AddHand(h *hand) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertHandTx(tx, h)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertHandTx(tx *sql.Tx, h *hand) error {
+	var number int
+	err := tx.QueryRow(nextHandNumber, h.MatchID).Scan(&number)
 	if err != nil {
 		return err
 	}
-	_, err = stmt.Exec(m.Team1, m.Team2, m.Score1, m.Score2, m.Id)
+	playedAt := time.Now().UTC()
+	_, err = tx.Exec(insertHand, h.MatchID, number, h.Points1, h.Points2, playedAt, h.Tranque, h.Domino)
 	if err != nil {
 		return err
 	}
+	for _, b := range h.Bonuses {
+		_, err = tx.Exec(insertHandBonus, h.MatchID, number, b.Team, b.Kind, b.Points)
+		if err != nil {
+			return err
+		}
+	}
+	h.Number = number
+	h.PlayedAt = playedAt
 	return nil
 }
 
-func (s *sqliteStore) AddPointsByID(id int64, score1 int, score2 int) (*match, error) {
+// ListHands returns the hands of the match in the order they were played.
+func (s *sqliteStore) ListHands(matchID int64) ([]hand, error) {
+	return queryHands(s.db, matchID)
+}
+
+func queryHands(q querier, matchID int64) ([]hand, error) {
+	rows, err := q.Query(listHands, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hands := []hand{}
+	for rows.Next() {
+		h := hand{MatchID: matchID, Bonuses: []awardedBonus{}}
+		err = rows.Scan(&h.Number, &h.Points1, &h.Points2, &h.PlayedAt, &h.Tranque, &h.Domino)
+		if err != nil {
+			return nil, err
+		}
+		hands = append(hands, h)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	byNumber := make(map[int]int, len(hands))
+	for i, h := range hands {
+		byNumber[h.Number] = i
+	}
+	bonusRows, err := q.Query(listHandBonuses, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer bonusRows.Close()
+	for bonusRows.Next() {
+		var (
+			number int
+			b      awardedBonus
+		)
+		err = bonusRows.Scan(&number, &b.Team, &b.Kind, &b.Points)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := byNumber[number]
+		if ok {
+			hands[i].Bonuses = append(hands[i].Bonuses, b)
+		}
+	}
+	if err = bonusRows.Err(); err != nil {
+		return nil, err
+	}
+	return hands, nil
+}
+
+// UndoLastHand removes the most recent hand of the match and takes its points
+// off the totals. A match that was over is reopened if the totals drop below
+// the target again.
+func (s *sqliteStore) UndoLastHand(id int64) (*match, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	m, err := readMatch(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.Archived() {
+		return nil, &MatchArchivedError{}
+	}
+	if len(m.Hands) == 0 {
+		return nil, &HandNotFoundError{}
+	}
+	before := *m
+	last := m.Hands[len(m.Hands)-1]
+	m.Score1 -= last.Points1
+	m.Score2 -= last.Points2
+	m.updateStatus()
+
+	_, err = tx.Exec(deleteHandBonuses, m.Id, last.Number)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(deleteHand, m.Id, last.Number)
+	if err != nil {
+		return nil, err
+	}
+	err = execUpdateMatch(tx, m)
+	if err != nil {
+		return nil, err
+	}
+	err = syncMatchTx(tx, before, *m)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	m.Hands = m.Hands[:len(m.Hands)-1]
+	return m, nil
+}
+
+// UpdateHand replaces hand number of the match with h, entered like the
+// hands of AddHandByID as raw points, bonuses claimed and whether it ended in
+// a tranque. The bonuses are valued again and the totals adjusted by the
+// difference. The player who won the hand is kept.
+func (s *sqliteStore) UpdateHand(id int64, number int, h hand) (*match, error) {
+	if h.Points1 < 0 || h.Points2 < 0 {
+		return nil, &NegativePointsError{}
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	m, err := readMatch(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.Archived() {
+		return nil, &MatchArchivedError{}
+	}
+	i := m.handIndex(number)
+	if i < 0 {
+		return nil, &HandNotFoundError{}
+	}
+	before := *m
+	old := m.Hands[i]
+	h.Domino = old.Domino
+	played := m.scoreHand(h)
+	played.Number, played.PlayedAt = old.Number, old.PlayedAt
+	m.Score1 += played.Points1 - old.Points1
+	m.Score2 += played.Points2 - old.Points2
+	m.updateStatus()
+
+	_, err = tx.Exec(updateHand, played.Points1, played.Points2, played.Tranque, m.Id, number)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(deleteHandBonuses, m.Id, number)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range played.Bonuses {
+		_, err = tx.Exec(insertHandBonus, m.Id, number, b.Team, b.Kind, b.Points)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = execUpdateMatch(tx, m)
+	if err != nil {
+		return nil, err
+	}
+	err = syncMatchTx(tx, before, *m)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	m.Hands[i] = played
+	return m, nil
+}
+
+// DeleteMatch archives the match. It disappears from listings but its data is
+// kept and it can be brought back with RestoreMatch.
+func (s *sqliteStore) DeleteMatch(id int64) error {
+	return s.setArchivedAt(id, nullTime(time.Now().UTC()))
+}
+
+// RestoreMatch brings an archived match back.
+func (s *sqliteStore) RestoreMatch(id int64) error {
+	return s.setArchivedAt(id, sql.NullTime{})
+}
+
+// setArchivedAt archives or restores the match. Archived matches do not
+// count for the ratings, so archiving or restoring a finished match rebuilds
+// them.
+func (s *sqliteStore) setArchivedAt(id int64, archivedAt sql.NullTime) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rs, err := tx.Exec(archiveMatch, archivedAt, id)
+	if err != nil {
+		return err
+	}
+	updated, err := rs.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return &MatchNotFoundError{}
+	}
+	var status matchStatus
+	err = tx.QueryRow(getMatchStatus, id).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if status == StatusFinished {
+		_, err = recomputeRatingsTx(tx)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
 
-	m, err := s.GetMatchByID(id)
+// AbandonMatch closes a match in progress without a winner.
+func (s *sqliteStore) AbandonMatch(id int64) (*match, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	if m.GameOver() {
+	m, err := readMatch(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !m.InProgress() {
 		return nil, &GameOverError{}
 	}
-	m.AddPoints(Team1, score1)
-	m.AddPoints(Team2, score2)
+	before := *m
+	m.Abandon()
 
-	err = s.UpdateMatch(m)
+	err = execUpdateMatch(tx, m)
+	if err != nil {
+		return nil, err
+	}
+	err = syncMatchTx(tx, before, *m)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
 	if err != nil {
 		return nil, err
 	}
@@ -91,46 +578,1709 @@ func (s *sqliteStore) AddPointsByID(id int64, score1 int, score2 int) (*match, e
 }
 
 func (s *sqliteStore) GetMatchByID(id int64) (*match, error) {
-	rows, err := s.db.Query(getMatch, id)
+	return readMatch(s.db, id)
+}
+
+// readMatch is GetMatchByID through q, so a transaction can read the match it
+// is about to change.
+func readMatch(q querier, id int64) (*match, error) {
+	rows, err := q.Query(getMatch, id)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	m := match{}
 
 	for rows.Next() {
-		var (
-			team1Name, team2Name   string
-			team1Score, team2Score int
-		)
-		err = rows.Scan(&team1Name, &team2Name, &team1Score, &team2Score)
+		m, err = scanMatch(rows)
 		if err != nil {
 			return nil, err
 		}
-		m.Id = id
-		m.Team1 = team1Name
-		m.Team2 = team2Name
-		m.Score1 = team1Score
-		m.Score2 = team2Score
 	}
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	if m.Id == 0 {
+		return &m, nil
+	}
+
+	m.Hands, err = queryHands(q, id)
+	if err != nil {
+		return nil, err
+	}
+	m.Players, err = queryMatchPlayers(q, id)
+	if err != nil {
+		return nil, err
+	}
 	return &m, nil
 }
 
-type GameOverError struct{}
-
-func (err *GameOverError) Error() string {
-	return "game over"
+// GetMatchByWatchToken returns the match shared with the spectator token. Like
+// GetMatchByID, an unknown token gives an empty match.
+func (s *sqliteStore) GetMatchByWatchToken(token string) (*match, error) {
+	var id int64
+	err := s.db.QueryRow(getMatchIDByWatchToken, token).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &match{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetMatchByID(id)
 }
 
-type sqliteStore struct {
-	db *sql.DB
+// GetMatchIDBySlug returns the ID of the match with the public slug, or
+// MatchNotFoundError.
+func (s *sqliteStore) GetMatchIDBySlug(slug string) (int64, error) {
+	var id int64
+	err := s.db.QueryRow(getMatchIDBySlug, slug).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, &MatchNotFoundError{}
+	}
+	return id, err
+}
+
+// CheckEditAccess returns EditForbiddenError unless secret is the edit secret
+// of the match or userID is its owner. Matches created before edit secrets
+// have none and only their owner can edit them until IssueEditSecrets gives
+// them one.
+func (s *sqliteStore) CheckEditAccess(id int64, secret string, userID int64) error {
+	var (
+		hash  sql.NullString
+		owner sql.NullInt64
+	)
+	err := s.db.QueryRow(getMatchEditAccess, id).Scan(&hash, &owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &MatchNotFoundError{}
+	}
+	if err != nil {
+		return err
+	}
+	//no secret hashes to an empty hash
+	return checkEditAccess(hash.String, owner.Int64, secret, userID)
+}
+
+// IssueEditSecrets gives an edit secret to every match created before edit
+// secrets and returns the new secrets by match slug. Only their hashes are
+// stored, the caller has to hand the secrets out.
+func (s *sqliteStore) IssueEditSecrets() (map[string]string, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(listMatchesWithoutEditSecret)
+	if err != nil {
+		return nil, err
+	}
+	slugs := []string{}
+	for rows.Next() {
+		var slug string
+		err = rows.Scan(&slug)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		slugs = append(slugs, slug)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	secrets := map[string]string{}
+	for _, slug := range slugs {
+		secret, err := randomToken(16)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(setMatchEditSecretHash, hashToken(secret), slug)
+		if err != nil {
+			return nil, err
+		}
+		secrets[slug] = secret
+	}
+	return secrets, tx.Commit()
+}
+
+// MatchFilter narrows down ListMatches, zero values do not filter. Matches
+// are listed newest first, Cursor continues a listing after the match with
+// that ID as returned by the previous page.
+type MatchFilter struct {
+	Team   string
+	Player string
+	// Teams and Players list more teams and players that must all have
+	// taken part in the match.
+	Teams   []string
+	Players []string
+	Status  matchStatus
+	// From and To bound the creation time of the matches, To is exclusive.
+	From, To time.Time
+	Cursor   int64
+	// Limit is the page size, a negative limit lists every match.
+	Limit int
+	// Archived lists the archived matches instead of the active ones.
+	Archived bool
+	// Owner lists only the matches of the user with this ID.
+	Owner int64
+}
+
+// DefaultPageSize is the number of matches listed when no limit is given.
+const DefaultPageSize = 20
+
+// ListMatches returns a page of matches passing filter and the cursor of the
+// next page, 0 when there are no more matches. Listed matches come with their
+// players but without hands.
+func (s *sqliteStore) ListMatches(filter MatchFilter) ([]match, int64, error) {
+	where := []string{"archivedAt IS NULL"}
+	if filter.Archived {
+		where = []string{"archivedAt IS NOT NULL"}
+	}
+	args := []any{}
+	for _, team := range append([]string{filter.Team}, filter.Teams...) {
+		if team == "" {
+			continue
+		}
+		where = append(where, "(team1name = ? COLLATE NOCASE OR team2name = ? COLLATE NOCASE)")
+		args = append(args, team, team)
+	}
+	for _, name := range append([]string{filter.Player}, filter.Players...) {
+		if name == "" {
+			continue
+		}
+		where = append(where, "ID IN (SELECT mp.matchID FROM match_player mp JOIN players p ON p.ID = mp.playerID WHERE p.name = ?)")
+		args = append(args, strings.TrimSpace(name))
+	}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "createdAt >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "createdAt < ?")
+		args = append(args, filter.To.UTC())
+	}
+	if filter.Owner != 0 {
+		where = append(where, "ownerID = ?")
+		args = append(args, filter.Owner)
+	}
+	if filter.Cursor > 0 {
+		where = append(where, "ID < ?")
+		args = append(args, filter.Cursor)
+	}
+	limit := filter.Limit
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+	if limit > 0 {
+		//one extra row tells whether there is a next page
+		args = append(args, limit+1)
+	} else {
+		args = append(args, -1)
+	}
+
+	query := fmt.Sprintf(listMatches, strings.Join(where, " AND "))
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	matches := []match{}
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		matches = append(matches, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var next int64
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+		next = matches[limit-1].Id
+	}
+	for i := range matches {
+		matches[i].Players, err = s.listMatchPlayers(matches[i].Id)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return matches, next, nil
+}
+
+// scanMatch reads a match selected with matchColumns.
+func scanMatch(rows *sql.Rows) (match, error) {
+	var (
+		m                     match
+		ruleSet, status       string
+		createdAt, finishedAt sql.NullTime
+		archivedAt            sql.NullTime
+		watchToken, slug      sql.NullString
+		ownerID               sql.NullInt64
+	)
+	err := rows.Scan(&m.Id, &m.Team1, &m.Team2, &m.Score1, &m.Score2, &m.Target, &ruleSet, &status, &createdAt, &finishedAt, &archivedAt, &watchToken, &slug, &ownerID)
+	if err != nil {
+		return match{}, err
+	}
+	m.Rules, err = RuleSetByName(ruleSet)
+	if err != nil {
+		return match{}, err
+	}
+	m.Status = matchStatus(status)
+	m.CreatedAt = createdAt.Time
+	m.FinishedAt = finishedAt.Time
+	m.ArchivedAt = archivedAt.Time
+	m.WatchToken = watchToken.String
+	m.Slug = slug.String
+	m.OwnerID = ownerID.Int64
+	return m, nil
+}
+
+func (s *sqliteStore) listMatchPlayers(matchID int64) ([]player, error) {
+	return queryMatchPlayers(s.db, matchID)
+}
+
+func queryMatchPlayers(q querier, matchID int64) ([]player, error) {
+	rows, err := q.Query(listMatchPlayers, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	players := []player{}
+	for rows.Next() {
+		p := player{}
+		err = rows.Scan(&p.Seat, &p.ID, &p.Name)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return players, nil
+}
+
+// playerByNameTx returns the registered player with the given name, names are
+// matched ignoring case and surrounding spaces. The player is registered if
+// it does not exist yet.
+func playerByNameTx(tx *sql.Tx, name string) (player, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return player{}, errors.New("player name cannot be empty")
+	}
+	_, err := tx.Exec(insertPlayerIfMissing, name)
+	if err != nil {
+		return player{}, err
+	}
+	p := player{}
+	err = tx.QueryRow(getPlayerByName, name).Scan(&p.ID, &p.Name)
+	if err != nil {
+		return player{}, err
+	}
+	return p, nil
+}
+
+// CreatePlayer registers a new player, it errors if the name is taken.
+func (s *sqliteStore) CreatePlayer(name string) (*player, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("player name cannot be empty")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = checkPlayerNameFreeTx(tx, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := tx.Exec(insertPlayer, name)
+	if err != nil {
+		return nil, err
+	}
+	id, err := rs.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &player{ID: id, Name: name}, nil
+}
+
+// GetPlayerByID returns the registered player, like GetMatchByID an unknown ID
+// gives an empty player.
+func (s *sqliteStore) GetPlayerByID(id int64) (*player, error) {
+	p := player{}
+	err := s.db.QueryRow(getPlayerByID, id).Scan(&p.ID, &p.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &player{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListPlayers returns the registered players whose name starts with prefix,
+// all of them when prefix is empty, sorted by name.
+func (s *sqliteStore) ListPlayers(prefix string) ([]player, error) {
+	rows, err := s.db.Query(listPlayers, escapeLike(strings.TrimSpace(prefix))+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	players := []player{}
+	for rows.Next() {
+		p := player{}
+		err = rows.Scan(&p.ID, &p.Name)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return players, nil
+}
+
+// RenamePlayer changes the name of a registered player. Every match the
+// player took part in shows the new name.
+func (s *sqliteStore) RenamePlayer(id int64, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("player name cannot be empty")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkPlayerNameFreeTx(tx, name, id)
+	if err != nil {
+		return err
+	}
+	rs, err := tx.Exec(renamePlayer, name, id)
+	if err != nil {
+		return err
+	}
+	updated, err := rs.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return &PlayerNotFoundError{}
+	}
+	return tx.Commit()
+}
+
+// MergePlayers moves the match history of player from onto player into and
+// removes from. It is meant to fix the same person registered twice.
+func (s *sqliteStore) MergePlayers(from, into int64) error {
+	if from == into {
+		return errors.New("cannot merge a player into itself")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range []int64{from, into} {
+		var exists int
+		err = tx.QueryRow(countPlayer, id).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists == 0 {
+			return &PlayerNotFoundError{}
+		}
+	}
+	var shared int
+	err = tx.QueryRow(countSharedMatches, from, into).Scan(&shared)
+	if err != nil {
+		return err
+	}
+	if shared > 0 {
+		return &PlayersShareMatchError{}
+	}
+	_, err = tx.Exec(reassignMatchPlayer, into, from)
+	if err != nil {
+		return err
+	}
+	// the ratings of both players are now the ratings of one
+	_, err = recomputeRatingsTx(tx)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(deletePlayer, from)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func checkPlayerNameFreeTx(tx *sql.Tx, name string, id int64) error {
+	var taken int
+	err := tx.QueryRow(countPlayersNamed, name, id).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken > 0 {
+		return &PlayerExistsError{}
+	}
+	return nil
+}
+
+// CreateUser registers a user with the password, stored as a bcrypt hash.
+func (s *sqliteStore) CreateUser(name, password string) (*user, error) {
+	name, err := normalizeUserName(name)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var taken int
+	err = tx.QueryRow(countUsersNamed, name).Scan(&taken)
+	if err != nil {
+		return nil, err
+	}
+	if taken > 0 {
+		return nil, &UserExistsError{}
+	}
+	u := user{Name: name, CreatedAt: time.Now().UTC()}
+	rs, err := tx.Exec(insertUser, u.Name, hash, u.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	u.ID, err = rs.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// AuthenticateUser returns the user with the name and password, or
+// InvalidCredentialsError without telling which one was wrong.
+func (s *sqliteStore) AuthenticateUser(name, password string) (*user, error) {
+	var (
+		u    user
+		hash string
+	)
+	err := s.db.QueryRow(getUserByName, strings.TrimSpace(name)).Scan(&u.ID, &u.Name, &u.CreatedAt, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &InvalidCredentialsError{}
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err != nil {
+		return nil, &InvalidCredentialsError{}
+	}
+	return &u, nil
+}
+
+// CreateSession logs the user in and returns the session token for the
+// cookie. Only the hash of the token is stored.
+func (s *sqliteStore) CreateSession(userID int64) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	_, err = s.db.Exec(insertSession, hashToken(token), userID, time.Now().UTC().Add(sessionDuration))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetUserBySession returns the user logged in with the session token. Like
+// GetMatchByID, an unknown or expired session gives an empty user.
+func (s *sqliteStore) GetUserBySession(token string) (*user, error) {
+	u := user{}
+	err := s.db.QueryRow(getUserBySession, hashToken(token), time.Now().UTC()).Scan(&u.ID, &u.Name, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &user{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// DeleteSession logs out the session with the token.
+func (s *sqliteStore) DeleteSession(token string) error {
+	_, err := s.db.Exec(deleteSession, hashToken(token))
+	return err
+}
+
+// CreateTournament stores t with its teams and schedule, and creates the
+// matches of the games that can be played right away. Like CreateMatch, it
+// gives the tournament a slug and an edit secret only known through
+// t.EditSecret. The edit secret also opens the matches of the tournament.
+func (s *sqliteStore) CreateTournament(t *tournament) error {
+	err := t.validate()
+	if err != nil {
+		return err
+	}
+	slug, err := randomToken(slugBytes)
+	if err != nil {
+		return err
+	}
+	editSecret, err := randomToken(16)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rs, err := tx.Exec(insertTournament, slug, t.Name, t.Format, t.Rules.Name(), t.Target, t.CreatedAt, hashToken(editSecret), nullID(t.OwnerID))
+	if err != nil {
+		return err
+	}
+	id, err := rs.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for seed, name := range t.Teams {
+		_, err = tx.Exec(insertTournamentTeam, id, seed+1, name)
+		if err != nil {
+			return err
+		}
+	}
+	for _, g := range t.schedule() {
+		_, err = tx.Exec(insertTournamentGame, id, g.Round, g.Slot, g.Team1, g.Team2)
+		if err != nil {
+			return err
+		}
+	}
+	err = settleTournamentTx(tx, id)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	t.ID = id
+	t.Slug = slug
+	t.EditSecret = editSecret
+	return nil
+}
+
+// GetTournamentBySlug returns the tournament with its games and their
+// matches. Like GetMatchByID, an unknown slug gives an empty tournament.
+func (s *sqliteStore) GetTournamentBySlug(slug string) (*tournament, error) {
+	var id int64
+	err := s.db.QueryRow(getTournamentIDBySlug, slug).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &tournament{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t, _, err := getTournament(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	for i, g := range t.Games {
+		if g.MatchID == 0 {
+			continue
+		}
+		t.Games[i].Match, err = s.GetMatchByID(g.MatchID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// ListTournaments returns the tournaments newest first, without their games.
+func (s *sqliteStore) ListTournaments() ([]tournament, error) {
+	rows, err := s.db.Query(listTournaments)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tournaments := []tournament{}
+	for rows.Next() {
+		t, _, err := scanTournament(rows)
+		if err != nil {
+			return nil, err
+		}
+		tournaments = append(tournaments, t)
+	}
+	return tournaments, rows.Err()
+}
+
+// CheckTournamentEditAccess is CheckEditAccess for a tournament.
+func (s *sqliteStore) CheckTournamentEditAccess(id int64, secret string, userID int64) error {
+	t, hash, err := getTournament(s.db, id)
+	if err != nil {
+		return err
+	}
+	return checkEditAccess(hash, t.OwnerID, secret, userID)
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// getTournament loads the tournament with its teams and games, without their
+// matches, and the hash of its edit secret.
+func getTournament(q querier, id int64) (*tournament, string, error) {
+	rows, err := q.Query(getTournamentByID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, "", err
+		}
+		return nil, "", &TournamentNotFoundError{}
+	}
+	t, hash, err := scanTournament(rows)
+	if err != nil {
+		return nil, "", err
+	}
+	rows.Close()
+
+	teams, err := q.Query(listTournamentTeams, id)
+	if err != nil {
+		return nil, "", err
+	}
+	defer teams.Close()
+	for teams.Next() {
+		var name string
+		err = teams.Scan(&name)
+		if err != nil {
+			return nil, "", err
+		}
+		t.Teams = append(t.Teams, name)
+	}
+	if err = teams.Err(); err != nil {
+		return nil, "", err
+	}
+
+	games, err := q.Query(listTournamentGames, id)
+	if err != nil {
+		return nil, "", err
+	}
+	defer games.Close()
+	for games.Next() {
+		var (
+			g       tournamentGame
+			matchID sql.NullInt64
+		)
+		err = games.Scan(&g.Round, &g.Slot, &g.Team1, &g.Team2, &g.Winner, &matchID)
+		if err != nil {
+			return nil, "", err
+		}
+		g.MatchID = matchID.Int64
+		t.Games = append(t.Games, g)
+	}
+	return &t, hash, games.Err()
+}
+
+// scanTournament reads a tournament selected with tournamentColumns.
+func scanTournament(rows *sql.Rows) (tournament, string, error) {
+	var (
+		t             tournament
+		format        string
+		ruleSet, hash string
+		ownerID       sql.NullInt64
+	)
+	err := rows.Scan(&t.ID, &t.Slug, &t.Name, &format, &ruleSet, &t.Target, &t.CreatedAt, &hash, &ownerID)
+	if err != nil {
+		return tournament{}, "", err
+	}
+	t.Format = tournamentFormat(format)
+	t.Rules, err = RuleSetByName(ruleSet)
+	if err != nil {
+		return tournament{}, "", err
+	}
+	t.OwnerID = ownerID.Int64
+	return t, hash, nil
+}
+
+// settleTournamentTx brings the games of the tournament in line with their
+// winners, see tournament.settle, and creates the matches of the games whose
+// teams just became known. When a winner is taken back or changes, the match
+// it had been moved on to is removed, unless it has already been played, and
+// a game with new teams gets a new match.
+func settleTournamentTx(tx *sql.Tx, id int64) error {
+	t, hash, err := getTournament(tx, id)
+	if err != nil {
+		return err
+	}
+	before := append([]tournamentGame{}, t.Games...)
+	t.settle()
+
+	for i := range t.Games {
+		g := &t.Games[i]
+		retracted := int64(0)
+		changed := g.Team1 != before[i].Team1 || g.Team2 != before[i].Team2
+		if g.MatchID != 0 && (!g.Ready() || changed) {
+			var hands int
+			err = tx.QueryRow(countHands, g.MatchID).Scan(&hands)
+			if err != nil {
+				return err
+			}
+			if hands > 0 {
+				return &TournamentAdvancedError{}
+			}
+			retracted, g.MatchID = g.MatchID, 0
+		}
+		if g.Ready() && g.MatchID == 0 && g.Winner == "" {
+			m := NewMatch(
+				MatchWithTeam1Name(g.Team1),
+				MatchWithTeam2Name(g.Team2),
+				MatchWithRuleSet(t.Rules),
+				MatchWithTargetScore(t.Target),
+				MatchWithOwner(t.OwnerID),
+			)
+			err = createMatchTx(tx, &m, hash)
+			if err != nil {
+				return err
+			}
+			g.MatchID = m.Id
+		}
+		if *g == before[i] {
+			continue
+		}
+		_, err = tx.Exec(updateTournamentGame, g.Team1, g.Team2, g.Winner, nullID(g.MatchID), id, g.Round, g.Slot)
+		if err != nil {
+			return err
+		}
+		if retracted != 0 {
+			_, err = tx.Exec(deleteMatch, retracted)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncTournamentTx records on its tournament game who won the match, or that
+// it was reopened, when a change took the match from before to after. An
+// abandoned match is left out of the game, which gets a new one to replay it.
+func syncTournamentTx(tx *sql.Tx, before, after match) error {
+	abandoned := after.Status == StatusAbandoned && before.Status != StatusAbandoned
+	if before.Winner() == after.Winner() && !abandoned {
+		return nil
+	}
+	var id int64
+	err := tx.QueryRow(getTournamentIDByMatch, after.Id).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if abandoned {
+		_, err = tx.Exec(unlinkTournamentGameMatch, after.Id)
+	} else {
+		_, err = tx.Exec(setTournamentGameWinner, after.Winner(), after.Id)
+	}
+	if err != nil {
+		return err
+	}
+	return settleTournamentTx(tx, id)
+}
+
+// syncMatchTx lets the tournament or series the match belongs to know that a
+// change took it from before to after.
+func syncMatchTx(tx *sql.Tx, before, after match) error {
+	err := syncTournamentTx(tx, before, after)
+	if err != nil {
+		return err
+	}
+	err = syncSeriesTx(tx, before, after)
+	if err != nil {
+		return err
+	}
+	return syncRatingsTx(tx, before, after)
+}
+
+// CreateSeries stores the series with its first match. Like CreateMatch, it
+// gives the series a slug and an edit secret only known through
+// s.EditSecret. The edit secret also opens the matches of the series.
+func (s *sqliteStore) CreateSeries(sr *series) error {
+	err := sr.validate()
+	if err != nil {
+		return err
+	}
+	slug, err := randomToken(slugBytes)
+	if err != nil {
+		return err
+	}
+	editSecret, err := randomToken(16)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rs, err := tx.Exec(insertSeries, slug, sr.Team1, sr.Team2, sr.BestOf, sr.Rules.Name(), sr.Target, sr.CreatedAt, hashToken(editSecret), nullID(sr.OwnerID))
+	if err != nil {
+		return err
+	}
+	id, err := rs.LastInsertId()
+	if err != nil {
+		return err
+	}
+	err = settleSeriesTx(tx, id)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	sr.ID = id
+	sr.Slug = slug
+	sr.EditSecret = editSecret
+	return nil
+}
+
+// GetSeriesBySlug returns the series with its matches. Like GetMatchByID, an
+// unknown slug gives an empty series.
+func (s *sqliteStore) GetSeriesBySlug(slug string) (*series, error) {
+	var id int64
+	err := s.db.QueryRow(getSeriesIDBySlug, slug).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &series{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sr, _, err := getSeries(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range sr.Matches {
+		full, err := s.GetMatchByID(m.Id)
+		if err != nil {
+			return nil, err
+		}
+		sr.Matches[i] = *full
+	}
+	return sr, nil
+}
+
+// CheckSeriesEditAccess is CheckEditAccess for a series.
+func (s *sqliteStore) CheckSeriesEditAccess(id int64, secret string, userID int64) error {
+	sr, hash, err := getSeries(s.db, id)
+	if err != nil {
+		return err
+	}
+	return checkEditAccess(hash, sr.OwnerID, secret, userID)
+}
+
+// getSeries loads the series with the scores of its matches, enough to tell
+// who won them, and the hash of its edit secret.
+func getSeries(q querier, id int64) (*series, string, error) {
+	var (
+		sr      series
+		ruleSet string
+		hash    string
+		ownerID sql.NullInt64
+	)
+	err := q.QueryRow(getSeriesByID, id).Scan(&sr.ID, &sr.Slug, &sr.Team1, &sr.Team2, &sr.BestOf, &ruleSet, &sr.Target, &sr.CreatedAt, &hash, &ownerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", &SeriesNotFoundError{}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	sr.Rules, err = RuleSetByName(ruleSet)
+	if err != nil {
+		return nil, "", err
+	}
+	sr.OwnerID = ownerID.Int64
+
+	rows, err := q.Query(listSeriesMatches, id)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m match
+		err = rows.Scan(&m.Id, &m.Score1, &m.Score2, &m.Target, &m.Status)
+		if err != nil {
+			return nil, "", err
+		}
+		sr.Matches = append(sr.Matches, m)
+	}
+	return &sr, hash, rows.Err()
+}
+
+// settleSeriesTx keeps one match in play until a team takes the series. The
+// next match is created when the last one ends, and matches created after a
+// match that was reopened are removed, unless they have already been played.
+func settleSeriesTx(tx *sql.Tx, id int64) error {
+	sr, hash, err := getSeries(tx, id)
+	if err != nil {
+		return err
+	}
+
+	keep := len(sr.Matches)
+	wins1, wins2 := 0, 0
+	for i, m := range sr.Matches {
+		switch m.Winner() {
+		case Team1:
+			wins1++
+		case Team2:
+			wins2++
+		}
+		if m.Status == StatusInProgress || wins1 >= sr.WinsNeeded() || wins2 >= sr.WinsNeeded() {
+			keep = i + 1
+			break
+		}
+	}
+	for _, m := range sr.Matches[keep:] {
+		var hands int
+		err = tx.QueryRow(countHands, m.Id).Scan(&hands)
+		if err != nil {
+			return err
+		}
+		if hands > 0 {
+			return &SeriesAdvancedError{}
+		}
+		_, err = tx.Exec(deleteSeriesMatch, m.Id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(deleteMatch, m.Id)
+		if err != nil {
+			return err
+		}
+	}
+	sr.Matches = sr.Matches[:keep]
+
+	if sr.Winner() != "" || (keep > 0 && sr.Matches[keep-1].Status == StatusInProgress) {
+		return nil
+	}
+	m := NewMatch(
+		MatchWithTeam1Name(sr.Team1),
+		MatchWithTeam2Name(sr.Team2),
+		MatchWithRuleSet(sr.Rules),
+		MatchWithTargetScore(sr.Target),
+		MatchWithOwner(sr.OwnerID),
+	)
+	err = createMatchTx(tx, &m, hash)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(insertSeriesMatch, id, keep+1, m.Id)
+	return err
+}
+
+// syncSeriesTx moves the series of the match on when the match ended, was
+// reopened or was abandoned, in which case it is replayed.
+func syncSeriesTx(tx *sql.Tx, before, after match) error {
+	if before.Winner() == after.Winner() && before.Status == after.Status {
+		return nil
+	}
+	var id int64
+	err := tx.QueryRow(getSeriesIDByMatch, after.Id).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return settleSeriesTx(tx, id)
+}
+
+// CreateLeague stores the league with its teams and seasons. Like
+// CreateMatch, it gives the league a slug and an edit secret only known
+// through l.EditSecret.
+func (s *sqliteStore) CreateLeague(l *league) error {
+	err := l.validate()
+	if err != nil {
+		return err
+	}
+	slug, err := randomToken(slugBytes)
+	if err != nil {
+		return err
+	}
+	editSecret, err := randomToken(16)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rs, err := tx.Exec(insertLeague, slug, l.Name, l.WinPoints, l.LossPoints, l.ShutoutPoints, l.CreatedAt, hashToken(editSecret), nullID(l.OwnerID))
+	if err != nil {
+		return err
+	}
+	id, err := rs.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, name := range l.Teams {
+		_, err = tx.Exec(insertLeagueTeam, id, name)
+		if err != nil {
+			return err
+		}
+	}
+	for i := range l.Seasons {
+		err = insertSeasonTx(tx, id, &l.Seasons[i])
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	l.ID = id
+	l.Slug = slug
+	l.EditSecret = editSecret
+	return nil
+}
+
+// AddSeason opens a new season of the league.
+func (s *sqliteStore) AddSeason(leagueID int64, sn *season) error {
+	err := sn.validate()
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertSeasonTx(tx, leagueID, sn)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertSeasonTx(tx *sql.Tx, leagueID int64, sn *season) error {
+	rs, err := tx.Exec(insertSeason, leagueID, sn.Name, sn.Start, nullTime(sn.End))
+	if err != nil {
+		return err
+	}
+	sn.ID, err = rs.LastInsertId()
+	return err
+}
+
+// GetLeagueBySlug returns the league with its teams and its seasons, newest
+// first. Like GetMatchByID, an unknown slug gives an empty league.
+func (s *sqliteStore) GetLeagueBySlug(slug string) (*league, error) {
+	var id int64
+	err := s.db.QueryRow(getLeagueIDBySlug, slug).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &league{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l, _, err := s.getLeague(id)
+	return l, err
+}
+
+// ListLeagues returns the leagues newest first, without teams or seasons.
+func (s *sqliteStore) ListLeagues() ([]league, error) {
+	rows, err := s.db.Query(listLeagues)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	leagues := []league{}
+	for rows.Next() {
+		var l league
+		err = rows.Scan(&l.ID, &l.Slug, &l.Name, &l.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		leagues = append(leagues, l)
+	}
+	return leagues, rows.Err()
+}
+
+// CheckLeagueEditAccess is CheckEditAccess for a league.
+func (s *sqliteStore) CheckLeagueEditAccess(id int64, secret string, userID int64) error {
+	l, hash, err := s.getLeague(id)
+	if err != nil {
+		return err
+	}
+	return checkEditAccess(hash, l.OwnerID, secret, userID)
+}
+
+// LeagueStandings computes the standings of a season from the finished
+// matches created during the season. Archived matches do not count.
+func (s *sqliteStore) LeagueStandings(leagueID, seasonID int64) ([]leagueStanding, error) {
+	l, _, err := s.getLeague(leagueID)
+	if err != nil {
+		return nil, err
+	}
+	var sn *season
+	for i := range l.Seasons {
+		if l.Seasons[i].ID == seasonID {
+			sn = &l.Seasons[i]
+		}
+	}
+	if sn == nil {
+		return nil, &SeasonNotFoundError{}
+	}
+
+	rows, err := s.db.Query(listSeasonMatches, StatusFinished, sn.Start, nullTime(sn.Until()), nullTime(sn.Until()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	matches := []match{}
+	for rows.Next() {
+		var m match
+		err = rows.Scan(&m.Team1, &m.Team2, &m.Score1, &m.Score2, &m.Target, &m.Status)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return l.Standings(matches), nil
+}
+
+// getLeague loads the league with its teams and seasons, and the hash of its
+// edit secret.
+func (s *sqliteStore) getLeague(id int64) (*league, string, error) {
+	var (
+		l       league
+		hash    string
+		ownerID sql.NullInt64
+	)
+	err := s.db.QueryRow(getLeagueByID, id).Scan(&l.ID, &l.Slug, &l.Name, &l.WinPoints, &l.LossPoints, &l.ShutoutPoints, &l.CreatedAt, &hash, &ownerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", &LeagueNotFoundError{}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	l.OwnerID = ownerID.Int64
+
+	teams, err := s.db.Query(listLeagueTeams, id)
+	if err != nil {
+		return nil, "", err
+	}
+	defer teams.Close()
+	for teams.Next() {
+		var name string
+		err = teams.Scan(&name)
+		if err != nil {
+			return nil, "", err
+		}
+		l.Teams = append(l.Teams, name)
+	}
+	if err = teams.Err(); err != nil {
+		return nil, "", err
+	}
+
+	seasons, err := s.db.Query(listSeasons, id)
+	if err != nil {
+		return nil, "", err
+	}
+	defer seasons.Close()
+	for seasons.Next() {
+		var (
+			sn  season
+			end sql.NullTime
+		)
+		err = seasons.Scan(&sn.ID, &sn.Name, &sn.Start, &end)
+		if err != nil {
+			return nil, "", err
+		}
+		sn.Start = sn.Start.UTC()
+		if end.Valid {
+			sn.End = end.Time.UTC()
+		}
+		l.Seasons = append(l.Seasons, sn)
+	}
+	return &l, hash, seasons.Err()
+}
+
+// syncRatingsTx rates the players of a match that just finished. When the
+// result of a rated match changes, ratings are rebuilt as the matches after
+// it were rated from the old result.
+func syncRatingsTx(tx *sql.Tx, before, after match) error {
+	if before.Winner() == after.Winner() {
+		return nil
+	}
+	if before.Winner() == "" {
+		return rateMatchTx(tx, after.Id, after.Winner(), after.FinishedAt)
+	}
+	_, err := recomputeRatingsTx(tx)
+	return err
+}
+
+// RecomputeRatings rebuilds every rating and its history from the finished
+// matches that are not archived, in the order they finished. It returns the
+// matches rated.
+func (s *sqliteStore) RecomputeRatings() (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rated, err := recomputeRatingsTx(tx)
+	if err != nil {
+		return 0, err
+	}
+	return rated, tx.Commit()
+}
+
+func recomputeRatingsTx(tx *sql.Tx) (int, error) {
+	for _, stmt := range []string{deleteRatingHistory, deleteRatings} {
+		_, err := tx.Exec(stmt)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	rows, err := tx.Query(listFinishedMatches, StatusFinished)
+	if err != nil {
+		return 0, err
+	}
+	finished := []match{}
+	for rows.Next() {
+		var (
+			m          match
+			finishedAt sql.NullTime
+		)
+		err = rows.Scan(&m.Id, &m.Score1, &m.Score2, &m.Target, &m.Status, &finishedAt)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		m.FinishedAt = finishedAt.Time
+		finished = append(finished, m)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, m := range finished {
+		err = rateMatchTx(tx, m.Id, m.Winner(), m.FinishedAt)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(finished), nil
+}
+
+// rateMatchTx moves the Elo ratings of the players of the match, each by the
+// average rating of their team against the other, and of both partnerships
+// when the four seats are taken. Matches missing the players of a team are
+// not rated, and a player seated twice is left out.
+func rateMatchTx(tx *sql.Tx, matchID int64, winner team, playedAt time.Time) error {
+	rows, err := tx.Query(listMatchPlayerIDs, matchID)
+	if err != nil {
+		return err
+	}
+	seats := []player{}
+	for rows.Next() {
+		var p player
+		err = rows.Scan(&p.Seat, &p.ID)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		seats = append(seats, p)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	//matches created before createMatchTx checked the seats can have a
+	//player twice, two rating rows for them would fail the hand
+	taken := map[int64]int{}
+	for _, p := range seats {
+		taken[p.ID]++
+	}
+	teams := map[team][]int64{}
+	for _, p := range seats {
+		if taken[p.ID] == 1 {
+			teams[p.Seat.Team()] = append(teams[p.Seat.Team()], p.ID)
+		}
+	}
+	if len(teams[Team1]) == 0 || len(teams[Team2]) == 0 {
+		return nil
+	}
+	score := map[team]float64{Team1: 0, Team2: 0}
+	score[winner] = 1
+	other := map[team]team{Team1: Team2, Team2: Team1}
+
+	players := map[team][]float64{}
+	for t, ids := range teams {
+		for _, id := range ids {
+			rating, err := ratingTx(tx, id, 0)
+			if err != nil {
+				return err
+			}
+			players[t] = append(players[t], rating)
+		}
+	}
+	for t, ids := range teams {
+		delta := eloDelta(average(players[t]), average(players[other[t]]), score[t])
+		for i, id := range ids {
+			err = setRatingTx(tx, matchID, id, 0, players[t][i], players[t][i]+delta, playedAt)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(teams[Team1]) != 2 || len(teams[Team2]) != 2 {
+		return nil
+	}
+	pairs := map[team]float64{}
+	for t, ids := range teams {
+		playerID, partnerID := pairOf(ids)
+		pairs[t], err = ratingTx(tx, playerID, partnerID)
+		if err != nil {
+			return err
+		}
+	}
+	for t, ids := range teams {
+		playerID, partnerID := pairOf(ids)
+		delta := eloDelta(pairs[t], pairs[other[t]], score[t])
+		err = setRatingTx(tx, matchID, playerID, partnerID, pairs[t], pairs[t]+delta, playedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pairOf returns the IDs of a partnership as it is stored, lower ID first.
+func pairOf(ids []int64) (int64, int64) {
+	if ids[0] < ids[1] {
+		return ids[0], ids[1]
+	}
+	return ids[1], ids[0]
+}
+
+// setRatingTx records the new rating of the player, or partnership, and the
+// change in the history of the match.
+func setRatingTx(tx *sql.Tx, matchID, playerID, partnerID int64, before, after float64, playedAt time.Time) error {
+	_, err := tx.Exec(upsertRating, playerID, partnerID, after, playedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(insertRatingHistory, matchID, playerID, partnerID, before, after, playedAt)
+	return err
+}
+
+// ratingTx returns the current rating of the player, or of the partnership
+// with partnerID when not 0.
+func ratingTx(tx *sql.Tx, playerID, partnerID int64) (float64, error) {
+	rating := InitialRating
+	err := tx.QueryRow(getRating, playerID, partnerID).Scan(&rating)
+	if errors.Is(err, sql.ErrNoRows) {
+		return InitialRating, nil
+	}
+	return rating, err
+}
+
+// ListPlayerRatings returns the players by rating, best first.
+func (s *sqliteStore) ListPlayerRatings() ([]playerRating, error) {
+	return s.listRatings(listPlayerRatings)
+}
+
+// ListPairRatings returns the partnerships by rating, best first.
+func (s *sqliteStore) ListPairRatings() ([]playerRating, error) {
+	return s.listRatings(listPairRatings)
+}
+
+func (s *sqliteStore) listRatings(query string) ([]playerRating, error) {
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := []playerRating{}
+	for rows.Next() {
+		var (
+			r           playerRating
+			partnerID   sql.NullInt64
+			partnerName sql.NullString
+		)
+		err = rows.Scan(&r.Player.ID, &r.Player.Name, &partnerID, &partnerName, &r.Rating, &r.Matches, &r.Updated)
+		if err != nil {
+			return nil, err
+		}
+		if partnerID.Valid {
+			r.Partner = &player{ID: partnerID.Int64, Name: partnerName.String}
+		}
+		r.Rank = len(ratings) + 1
+		ratings = append(ratings, r)
+	}
+	return ratings, rows.Err()
+}
+
+// ListRatingHistory returns how the rating of the player changed, latest
+// match first.
+func (s *sqliteStore) ListRatingHistory(playerID int64) ([]ratingChange, error) {
+	rows, err := s.db.Query(listRatingHistory, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []ratingChange{}
+	for rows.Next() {
+		var c ratingChange
+		err = rows.Scan(&c.MatchSlug, &c.Team1, &c.Team2, &c.Before, &c.After, &c.PlayedAt)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// PlayerStats gathers the finished matches the player sat at, with their
+// hands. Archived matches are left out.
+func (s *sqliteStore) PlayerStats(playerID int64) (*stats, error) {
+	p, err := s.GetPlayerByID(playerID)
+	if err != nil {
+		return nil, err
+	}
+	if p.ID == 0 {
+		return nil, &PlayerNotFoundError{}
+	}
+	return s.stats(p.Name, listPlayerStatsMatches, StatusFinished, playerID)
+}
+
+// TeamStats gathers the finished matches of the team, by name regardless of
+// case, with their hands. Archived matches are left out.
+func (s *sqliteStore) TeamStats(name string) (*stats, error) {
+	name = strings.TrimSpace(name)
+	return s.stats(name, listTeamStatsMatches, name, StatusFinished, name, name)
+}
+
+// stats replays the matches listed by query, which selects their ID and the
+// side of the subject of the stats, oldest first.
+func (s *sqliteStore) stats(name, query string, args ...any) (*stats, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	type side struct {
+		matchID int64
+		team    team
+	}
+	sides := []side{}
+	for rows.Next() {
+		var sd side
+		err = rows.Scan(&sd.matchID, &sd.team)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		sides = append(sides, sd)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	st := stats{Name: name, Results: []statsResult{}}
+	for _, sd := range sides {
+		m, err := s.GetMatchByID(sd.matchID)
+		if err != nil {
+			return nil, err
+		}
+		st.Results = append(st.Results, newStatsResult(*m, sd.team))
+	}
+	return &st, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s, the queries use \ as escape.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+type GameOverError struct{}
+
+func (err *GameOverError) Error() string {
+	return "game over"
+}
+
+type MatchNotFoundError struct{}
+
+func (err *MatchNotFoundError) Error() string {
+	return "match not found"
+}
+
+type MatchArchivedError struct{}
+
+func (err *MatchArchivedError) Error() string {
+	return "match is archived"
+}
+
+type HandNotFoundError struct{}
+
+func (err *HandNotFoundError) Error() string {
+	return "hand not found"
+}
+
+type NegativePointsError struct{}
+
+func (err *NegativePointsError) Error() string {
+	return "points cannot be negative"
+}
+
+type EditForbiddenError struct{}
+
+func (err *EditForbiddenError) Error() string {
+	return "the edit secret of the match is required"
+}
+
+type UserExistsError struct{}
+
+func (err *UserExistsError) Error() string {
+	return "a user with that name already exists"
+}
+
+type PasswordTooLongError struct{}
+
+func (err *PasswordTooLongError) Error() string {
+	return "password is too long"
+}
+
+type InvalidCredentialsError struct{}
+
+func (err *InvalidCredentialsError) Error() string {
+	return "wrong user name or password"
+}
+
+type TournamentNotFoundError struct{}
+
+func (err *TournamentNotFoundError) Error() string {
+	return "tournament not found"
+}
+
+type TournamentAdvancedError struct{}
+
+func (err *TournamentAdvancedError) Error() string {
+	return "the winner has already played the next round of the tournament"
+}
+
+type SeriesNotFoundError struct{}
+
+func (err *SeriesNotFoundError) Error() string {
+	return "series not found"
+}
+
+type SeriesAdvancedError struct{}
+
+func (err *SeriesAdvancedError) Error() string {
+	return "the next match of the series has already been played"
+}
+
+type LeagueNotFoundError struct{}
+
+func (err *LeagueNotFoundError) Error() string {
+	return "league not found"
+}
+
+type SeasonNotFoundError struct{}
+
+func (err *SeasonNotFoundError) Error() string {
+	return "season not found"
+}
+
+type PlayerNotFoundError struct{}
+
+func (err *PlayerNotFoundError) Error() string {
+	return "player not found"
+}
+
+type PlayerExistsError struct{}
+
+func (err *PlayerExistsError) Error() string {
+	return "a player with that name already exists"
+}
+
+// PlayersShareMatchError is returned seating the same player twice in a match
+// or merging two players who sat at the same match, as one person cannot take
+// two seats.
+type PlayersShareMatchError struct{}
+
+func (err *PlayersShareMatchError) Error() string {
+	return "a player cannot take two seats in the same match"
+}
+
+type sqliteStore struct {
+	db *sql.DB
 }
 
 const pragmaWALEnabled = `PRAGMA journal_mode = WAL;`
-const pragma500BusyTimeout = `PRAGMA busy_timeout = 5000;`
-const pragmaForeignKeysON = `PRAGMA foreign_keys = on;`
+
+// connectionParams set up every connection of the pool: wait on a busy
+// database instead of failing, enforce foreign keys and take the write lock
+// when a transaction begins, so a read and the write based on it cannot be
+// interleaved with another writer.
+const connectionParams = `?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_txlock=immediate`
 
 const createMatchTable = `
 CREATE TABLE IF NOT EXISTS match(
@@ -141,6 +2291,371 @@ team1Score INTEGER NOT NULL DEFAULT 0,
 team2Score INTEGER NOT NULL DEFAULT 0
 );`
 
-const insertMatch = `INSERT INTO match(team1name, team2name) VALUES (?, ?);`
-const updateMatch = `UPDATE match SET team1name = ?, team2name = ?, team1score = ?, team2score = ? WHERE ID = ?;`
-const getMatch = `SELECT team1name, team2name, team1score, team2score FROM  match WHERE ID = ?;`
+// migrations must only ever be appended to, a released migration is never
+// edited as databases in the wild have already applied it.
+var migrations = []string{
+	createMatchTable,
+	createHandTable,
+	addMatchTargetScore,
+	addMatchStatus,
+	addMatchCreatedAt,
+	addMatchFinishedAt,
+	finishLegacyMatches,
+	addMatchRuleSet,
+	createHandBonusTable,
+	addHandTranque,
+	createMatchPlayerTable,
+	addHandDomino,
+	createPlayersTable,
+	addMatchPlayerPlayerID,
+	registerMatchPlayers,
+	linkMatchPlayers,
+	dropMatchPlayerName,
+	addMatchArchivedAt,
+	addMatchWatchToken,
+	fillWatchTokens,
+	indexWatchTokens,
+	addMatchSlug,
+	fillMatchSlugs,
+	indexMatchSlugs,
+	addMatchEditSecretHash,
+	createUsersTable,
+	createSessionsTable,
+	addMatchOwnerID,
+	createTournamentTable,
+	createTournamentTeamTable,
+	createTournamentGameTable,
+	createSeriesTable,
+	createSeriesMatchTable,
+	createLeagueTable,
+	createLeagueTeamTable,
+	createSeasonTable,
+	createRatingTable,
+	createRatingHistoryTable,
+	fillLegacyCreatedAt,
+	fillLegacyFinishedAt,
+}
+
+const getUserVersion = `PRAGMA user_version;`
+const setUserVersion = `PRAGMA user_version = %d;`
+
+const addMatchTargetScore = `ALTER TABLE match ADD COLUMN targetScore INTEGER NOT NULL DEFAULT 200;`
+
+const addMatchStatus = `ALTER TABLE match ADD COLUMN status TEXT NOT NULL DEFAULT 'in_progress';`
+const addMatchCreatedAt = `ALTER TABLE match ADD COLUMN createdAt DATETIME;`
+const addMatchFinishedAt = `ALTER TABLE match ADD COLUMN finishedAt DATETIME;`
+const finishLegacyMatches = `UPDATE match SET status = 'finished' WHERE team1Score >= targetScore OR team2Score >= targetScore;`
+
+// Matches from before timestamps get the oldest known creation time, or the
+// time of the migration, and finish when they were created.
+const fillLegacyCreatedAt = `UPDATE match SET createdAt = COALESCE((SELECT MIN(createdAt) FROM match), CURRENT_TIMESTAMP) WHERE createdAt IS NULL;`
+const fillLegacyFinishedAt = `UPDATE match SET finishedAt = createdAt WHERE status = 'finished' AND finishedAt IS NULL;`
+
+const addMatchRuleSet = `ALTER TABLE match ADD COLUMN ruleSet TEXT NOT NULL DEFAULT 'dominicano';`
+
+const addMatchArchivedAt = `ALTER TABLE match ADD COLUMN archivedAt DATETIME;`
+const archiveMatch = `UPDATE match SET archivedAt = ? WHERE ID = ?;`
+const getMatchStatus = `SELECT status FROM match WHERE ID = ?;`
+
+// Matches created before spectator links get a random token of the same
+// shape as newWatchToken.
+const addMatchWatchToken = `ALTER TABLE match ADD COLUMN watchToken TEXT;`
+const fillWatchTokens = `UPDATE match SET watchToken = lower(hex(randomblob(16))) WHERE watchToken IS NULL;`
+const indexWatchTokens = `CREATE UNIQUE INDEX match_watch_token ON match(watchToken);`
+const getMatchIDByWatchToken = `SELECT ID FROM match WHERE watchToken = ?;`
+
+// Existing matches get a slug of slugBytes random bytes and no edit secret.
+const addMatchSlug = `ALTER TABLE match ADD COLUMN slug TEXT;`
+const fillMatchSlugs = `UPDATE match SET slug = lower(hex(randomblob(8))) WHERE slug IS NULL;`
+const indexMatchSlugs = `CREATE UNIQUE INDEX match_slug ON match(slug);`
+const addMatchEditSecretHash = `ALTER TABLE match ADD COLUMN editSecretHash TEXT;`
+const getMatchIDBySlug = `SELECT ID FROM match WHERE slug = ?;`
+const getMatchEditAccess = `SELECT editSecretHash, ownerID FROM match WHERE ID = ?;`
+const listMatchesWithoutEditSecret = `SELECT slug FROM match WHERE editSecretHash IS NULL ORDER BY ID;`
+const setMatchEditSecretHash = `UPDATE match SET editSecretHash = ? WHERE slug = ?;`
+
+const insertMatch = `INSERT INTO match(team1name, team2name, targetScore, ruleSet, status, createdAt, watchToken, slug, editSecretHash, ownerID) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+const updateMatch = `UPDATE match SET team1name = ?, team2name = ?, team1score = ?, team2score = ?, status = ?, finishedAt = ? WHERE ID = ?;`
+const matchColumns = `ID, team1name, team2name, team1score, team2score, targetScore, ruleSet, status, createdAt, finishedAt, archivedAt, watchToken, slug, ownerID`
+const getMatch = `SELECT ` + matchColumns + ` FROM  match WHERE ID = ?;`
+
+// listMatches is completed with the WHERE conditions of the filter.
+const listMatches = `SELECT ` + matchColumns + ` FROM match WHERE %s ORDER BY ID DESC LIMIT ?;`
+
+const createHandTable = `
+CREATE TABLE IF NOT EXISTS hand(
+matchID INTEGER NOT NULL REFERENCES match(ID),
+number INTEGER NOT NULL,
+team1Points INTEGER NOT NULL DEFAULT 0,
+team2Points INTEGER NOT NULL DEFAULT 0,
+playedAt DATETIME NOT NULL,
+PRIMARY KEY (matchID, number)
+);`
+
+const nextHandNumber = `SELECT COALESCE(MAX(number), 0) + 1 FROM hand WHERE matchID = ?;`
+const addHandTranque = `ALTER TABLE hand ADD COLUMN tranque BOOLEAN NOT NULL DEFAULT FALSE;`
+
+const addHandDomino = `ALTER TABLE hand ADD COLUMN domino TEXT NOT NULL DEFAULT '';`
+
+const insertHand = `INSERT INTO hand(matchID, number, team1Points, team2Points, playedAt, tranque, domino) VALUES (?, ?, ?, ?, ?, ?, ?);`
+const createMatchPlayerTable = `
+CREATE TABLE IF NOT EXISTS match_player(
+matchID INTEGER NOT NULL REFERENCES match(ID),
+seat TEXT NOT NULL,
+name TEXT NOT NULL,
+PRIMARY KEY (matchID, seat)
+);`
+
+const createUsersTable = `
+CREATE TABLE IF NOT EXISTS users(
+ID INTEGER NOT NULL PRIMARY KEY,
+name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+passwordHash TEXT NOT NULL,
+createdAt DATETIME NOT NULL
+);`
+
+const createSessionsTable = `
+CREATE TABLE IF NOT EXISTS sessions(
+tokenHash TEXT NOT NULL PRIMARY KEY,
+userID INTEGER NOT NULL REFERENCES users(ID),
+expiresAt DATETIME NOT NULL
+);`
+
+const addMatchOwnerID = `ALTER TABLE match ADD COLUMN ownerID INTEGER REFERENCES users(ID);`
+
+const countUsersNamed = `SELECT COUNT(*) FROM users WHERE name = ?;`
+const insertUser = `INSERT INTO users(name, passwordHash, createdAt) VALUES (?, ?, ?);`
+const getUserByName = `SELECT ID, name, createdAt, passwordHash FROM users WHERE name = ?;`
+const insertSession = `INSERT INTO sessions(tokenHash, userID, expiresAt) VALUES (?, ?, ?);`
+const getUserBySession = `
+SELECT users.ID, users.name, users.createdAt FROM sessions
+JOIN users ON users.ID = sessions.userID
+WHERE sessions.tokenHash = ? AND sessions.expiresAt > ?;`
+const deleteSession = `DELETE FROM sessions WHERE tokenHash = ?;`
+
+const createTournamentTable = `
+CREATE TABLE IF NOT EXISTS tournament(
+ID INTEGER NOT NULL PRIMARY KEY,
+slug TEXT NOT NULL UNIQUE,
+name TEXT NOT NULL,
+format TEXT NOT NULL,
+ruleSet TEXT NOT NULL,
+targetScore INTEGER NOT NULL,
+createdAt DATETIME NOT NULL,
+editSecretHash TEXT NOT NULL,
+ownerID INTEGER REFERENCES users(ID)
+);`
+
+const createTournamentTeamTable = `
+CREATE TABLE IF NOT EXISTS tournament_team(
+tournamentID INTEGER NOT NULL REFERENCES tournament(ID),
+seed INTEGER NOT NULL,
+name TEXT NOT NULL,
+PRIMARY KEY (tournamentID, seed)
+);`
+
+const createTournamentGameTable = `
+CREATE TABLE IF NOT EXISTS tournament_game(
+tournamentID INTEGER NOT NULL REFERENCES tournament(ID),
+round INTEGER NOT NULL,
+slot INTEGER NOT NULL,
+team1 TEXT NOT NULL DEFAULT '',
+team2 TEXT NOT NULL DEFAULT '',
+winner TEXT NOT NULL DEFAULT '',
+matchID INTEGER UNIQUE REFERENCES match(ID),
+PRIMARY KEY (tournamentID, round, slot)
+);`
+
+const tournamentColumns = `ID, slug, name, format, ruleSet, targetScore, createdAt, editSecretHash, ownerID`
+const insertTournament = `INSERT INTO tournament(slug, name, format, ruleSet, targetScore, createdAt, editSecretHash, ownerID) VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+const getTournamentByID = `SELECT ` + tournamentColumns + ` FROM tournament WHERE ID = ?;`
+const getTournamentIDBySlug = `SELECT ID FROM tournament WHERE slug = ?;`
+const listTournaments = `SELECT ` + tournamentColumns + ` FROM tournament ORDER BY ID DESC;`
+const insertTournamentTeam = `INSERT INTO tournament_team(tournamentID, seed, name) VALUES (?, ?, ?);`
+const listTournamentTeams = `SELECT name FROM tournament_team WHERE tournamentID = ? ORDER BY seed;`
+const insertTournamentGame = `INSERT INTO tournament_game(tournamentID, round, slot, team1, team2) VALUES (?, ?, ?, ?, ?);`
+const listTournamentGames = `SELECT round, slot, team1, team2, winner, matchID FROM tournament_game WHERE tournamentID = ? ORDER BY round, slot;`
+const updateTournamentGame = `UPDATE tournament_game SET team1 = ?, team2 = ?, winner = ?, matchID = ? WHERE tournamentID = ? AND round = ? AND slot = ?;`
+const getTournamentIDByMatch = `SELECT tournamentID FROM tournament_game WHERE matchID = ?;`
+const setTournamentGameWinner = `UPDATE tournament_game SET winner = ? WHERE matchID = ?;`
+const unlinkTournamentGameMatch = `UPDATE tournament_game SET matchID = NULL WHERE matchID = ?;`
+const countHands = `SELECT COUNT(*) FROM hand WHERE matchID = ?;`
+const deleteMatch = `DELETE FROM match WHERE ID = ?;`
+
+const createSeriesTable = `
+CREATE TABLE IF NOT EXISTS series(
+ID INTEGER NOT NULL PRIMARY KEY,
+slug TEXT NOT NULL UNIQUE,
+team1name TEXT NOT NULL,
+team2name TEXT NOT NULL,
+bestOf INTEGER NOT NULL,
+ruleSet TEXT NOT NULL,
+targetScore INTEGER NOT NULL,
+createdAt DATETIME NOT NULL,
+editSecretHash TEXT NOT NULL,
+ownerID INTEGER REFERENCES users(ID)
+);`
+
+const createSeriesMatchTable = `
+CREATE TABLE IF NOT EXISTS series_match(
+seriesID INTEGER NOT NULL REFERENCES series(ID),
+number INTEGER NOT NULL,
+matchID INTEGER NOT NULL UNIQUE REFERENCES match(ID),
+PRIMARY KEY (seriesID, number)
+);`
+
+const insertSeries = `INSERT INTO series(slug, team1name, team2name, bestOf, ruleSet, targetScore, createdAt, editSecretHash, ownerID) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+const getSeriesByID = `SELECT ID, slug, team1name, team2name, bestOf, ruleSet, targetScore, createdAt, editSecretHash, ownerID FROM series WHERE ID = ?;`
+const getSeriesIDBySlug = `SELECT ID FROM series WHERE slug = ?;`
+const getSeriesIDByMatch = `SELECT seriesID FROM series_match WHERE matchID = ?;`
+const listSeriesMatches = `SELECT m.ID, m.team1score, m.team2score, m.targetScore, m.status FROM series_match s JOIN match m ON m.ID = s.matchID WHERE s.seriesID = ? ORDER BY s.number;`
+const insertSeriesMatch = `INSERT INTO series_match(seriesID, number, matchID) VALUES (?, ?, ?);`
+const deleteSeriesMatch = `DELETE FROM series_match WHERE matchID = ?;`
+
+const createLeagueTable = `
+CREATE TABLE IF NOT EXISTS league(
+ID INTEGER NOT NULL PRIMARY KEY,
+slug TEXT NOT NULL UNIQUE,
+name TEXT NOT NULL,
+winPoints INTEGER NOT NULL,
+lossPoints INTEGER NOT NULL,
+shutoutPoints INTEGER NOT NULL,
+createdAt DATETIME NOT NULL,
+editSecretHash TEXT NOT NULL,
+ownerID INTEGER REFERENCES users(ID)
+);`
+
+const createLeagueTeamTable = `
+CREATE TABLE IF NOT EXISTS league_team(
+leagueID INTEGER NOT NULL REFERENCES league(ID),
+name TEXT NOT NULL,
+PRIMARY KEY (leagueID, name)
+);`
+
+const createSeasonTable = `
+CREATE TABLE IF NOT EXISTS season(
+ID INTEGER NOT NULL PRIMARY KEY,
+leagueID INTEGER NOT NULL REFERENCES league(ID),
+name TEXT NOT NULL,
+startsOn DATETIME NOT NULL,
+endsOn DATETIME
+);`
+
+const insertLeague = `INSERT INTO league(slug, name, winPoints, lossPoints, shutoutPoints, createdAt, editSecretHash, ownerID) VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+const getLeagueByID = `SELECT ID, slug, name, winPoints, lossPoints, shutoutPoints, createdAt, editSecretHash, ownerID FROM league WHERE ID = ?;`
+const getLeagueIDBySlug = `SELECT ID FROM league WHERE slug = ?;`
+const listLeagues = `SELECT ID, slug, name, createdAt FROM league ORDER BY ID DESC;`
+const insertLeagueTeam = `INSERT INTO league_team(leagueID, name) VALUES (?, ?);`
+const listLeagueTeams = `SELECT name FROM league_team WHERE leagueID = ? ORDER BY rowid;`
+const insertSeason = `INSERT INTO season(leagueID, name, startsOn, endsOn) VALUES (?, ?, ?, ?);`
+const listSeasons = `SELECT ID, name, startsOn, endsOn FROM season WHERE leagueID = ? ORDER BY startsOn DESC, ID DESC;`
+
+// listSeasonMatches gets the finished matches created from a start time up
+// to an optional exclusive end time.
+const listSeasonMatches = `SELECT team1name, team2name, team1score, team2score, targetScore, status FROM match WHERE status = ? AND archivedAt IS NULL AND createdAt >= ? AND (? IS NULL OR createdAt < ?);`
+
+// createRatingTable holds the rating of each player, with partnerID 0, and
+// of each partnership, with the lower player ID first.
+const createRatingTable = `
+CREATE TABLE IF NOT EXISTS rating(
+playerID INTEGER NOT NULL REFERENCES players(ID),
+partnerID INTEGER NOT NULL DEFAULT 0,
+rating REAL NOT NULL,
+matches INTEGER NOT NULL DEFAULT 0,
+updatedAt DATETIME NOT NULL,
+PRIMARY KEY (playerID, partnerID)
+);`
+
+const createRatingHistoryTable = `
+CREATE TABLE IF NOT EXISTS rating_history(
+matchID INTEGER NOT NULL REFERENCES match(ID),
+playerID INTEGER NOT NULL REFERENCES players(ID),
+partnerID INTEGER NOT NULL DEFAULT 0,
+before REAL NOT NULL,
+after REAL NOT NULL,
+playedAt DATETIME NOT NULL,
+PRIMARY KEY (matchID, playerID, partnerID)
+);`
+
+const getRating = `SELECT rating FROM rating WHERE playerID = ? AND partnerID = ?;`
+const upsertRating = `
+INSERT INTO rating(playerID, partnerID, rating, matches, updatedAt) VALUES (?, ?, ?, 1, ?)
+ON CONFLICT(playerID, partnerID) DO UPDATE SET rating = excluded.rating, matches = matches + 1, updatedAt = excluded.updatedAt;`
+const insertRatingHistory = `INSERT INTO rating_history(matchID, playerID, partnerID, before, after, playedAt) VALUES (?, ?, ?, ?, ?, ?);`
+const deleteRatings = `DELETE FROM rating;`
+const deleteRatingHistory = `DELETE FROM rating_history;`
+const listMatchPlayerIDs = `SELECT seat, playerID FROM match_player WHERE matchID = ? ORDER BY rowid;`
+const listFinishedMatches = `SELECT ID, team1score, team2score, targetScore, status, finishedAt FROM match WHERE status = ? AND archivedAt IS NULL ORDER BY finishedAt, ID;`
+const listPlayerRatings = `
+SELECT p.ID, p.name, NULL, NULL, r.rating, r.matches, r.updatedAt FROM rating r
+JOIN players p ON p.ID = r.playerID
+WHERE r.partnerID = 0 ORDER BY r.rating DESC, p.name;`
+const listPairRatings = `
+SELECT p.ID, p.name, q.ID, q.name, r.rating, r.matches, r.updatedAt FROM rating r
+JOIN players p ON p.ID = r.playerID
+JOIN players q ON q.ID = r.partnerID
+ORDER BY r.rating DESC, p.name;`
+const listRatingHistory = `
+SELECT m.slug, m.team1name, m.team2name, h.before, h.after, h.playedAt FROM rating_history h
+JOIN match m ON m.ID = h.matchID
+WHERE h.playerID = ? AND h.partnerID = 0 ORDER BY h.playedAt DESC, h.matchID DESC;`
+
+const listPlayerStatsMatches = `
+SELECT m.ID, CASE WHEN mp.seat IN ('north', 'south') THEN 'Team1' ELSE 'Team2' END FROM match m
+JOIN match_player mp ON mp.matchID = m.ID
+WHERE m.status = ? AND m.archivedAt IS NULL AND mp.playerID = ?
+ORDER BY m.finishedAt, m.ID;`
+const listTeamStatsMatches = `
+SELECT ID, CASE WHEN team1name = ? COLLATE NOCASE THEN 'Team1' ELSE 'Team2' END FROM match
+WHERE status = ? AND archivedAt IS NULL AND (team1name = ? COLLATE NOCASE OR team2name = ? COLLATE NOCASE)
+ORDER BY finishedAt, ID;`
+
+const createPlayersTable = `
+CREATE TABLE IF NOT EXISTS players(
+ID INTEGER NOT NULL PRIMARY KEY,
+name TEXT NOT NULL UNIQUE COLLATE NOCASE
+);`
+
+const addMatchPlayerPlayerID = `ALTER TABLE match_player ADD COLUMN playerID INTEGER REFERENCES players(ID);`
+const registerMatchPlayers = `INSERT OR IGNORE INTO players(name) SELECT DISTINCT trim(name) FROM match_player;`
+const linkMatchPlayers = `UPDATE match_player SET playerID = (SELECT ID FROM players WHERE players.name = trim(match_player.name));`
+const dropMatchPlayerName = `ALTER TABLE match_player DROP COLUMN name;`
+
+const insertMatchPlayer = `INSERT INTO match_player(matchID, seat, playerID) VALUES (?, ?, ?);`
+const listMatchPlayers = `
+SELECT mp.seat, p.ID, p.name FROM match_player mp
+JOIN players p ON p.ID = mp.playerID
+WHERE mp.matchID = ? ORDER BY mp.rowid;`
+const reassignMatchPlayer = `UPDATE match_player SET playerID = ? WHERE playerID = ?;`
+const countSharedMatches = `
+SELECT COUNT(*) FROM match_player a
+JOIN match_player b ON b.matchID = a.matchID
+WHERE a.playerID = ? AND b.playerID = ?;`
+
+const insertPlayer = `INSERT INTO players(name) VALUES (?);`
+const insertPlayerIfMissing = `INSERT INTO players(name) VALUES (?) ON CONFLICT(name) DO NOTHING;`
+const getPlayerByName = `SELECT ID, name FROM players WHERE name = ?;`
+const getPlayerByID = `SELECT ID, name FROM players WHERE ID = ?;`
+const listPlayers = `SELECT ID, name FROM players WHERE name LIKE ? ESCAPE '\' ORDER BY name;`
+const countPlayer = `SELECT COUNT(*) FROM players WHERE ID = ?;`
+const countPlayersNamed = `SELECT COUNT(*) FROM players WHERE name = ? AND ID != ?;`
+const renamePlayer = `UPDATE players SET name = ? WHERE ID = ?;`
+const deletePlayer = `DELETE FROM players WHERE ID = ?;`
+
+const createHandBonusTable = `
+CREATE TABLE IF NOT EXISTS hand_bonus(
+matchID INTEGER NOT NULL,
+handNumber INTEGER NOT NULL,
+team TEXT NOT NULL,
+kind TEXT NOT NULL,
+points INTEGER NOT NULL,
+FOREIGN KEY (matchID, handNumber) REFERENCES hand(matchID, number)
+);`
+
+const insertHandBonus = `INSERT INTO hand_bonus(matchID, handNumber, team, kind, points) VALUES (?, ?, ?, ?, ?);`
+const listHandBonuses = `SELECT handNumber, team, kind, points FROM hand_bonus WHERE matchID = ? ORDER BY handNumber;`
+const deleteHandBonuses = `DELETE FROM hand_bonus WHERE matchID = ? AND handNumber = ?;`
+
+const updateHand = `UPDATE hand SET team1Points = ?, team2Points = ?, tranque = ? WHERE matchID = ? AND number = ?;`
+const deleteHand = `DELETE FROM hand WHERE matchID = ? AND number = ?;`
+const listHands = `SELECT number, team1Points, team2Points, playedAt, tranque, domino FROM hand WHERE matchID = ? ORDER BY number;`
@@ -1,8 +1,14 @@
 package dominocount_test
 
 import (
+	"database/sql"
 	"dominocount"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
 )
 
 func TestSQLiteStore_MatchRoundtripCreateUpdateGet(t *testing.T) {
@@ -108,3 +114,1100 @@ func TestSQLiteStore_MatchAddPointsErrorsAfter200(t *testing.T) {
 	}
 
 }
+
+func TestSQLiteStore_AddPointsRecordsEachHand(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, points := range [][2]int{{30, 0}, {0, 45}, {12, 0}} {
+		_, err = store.AddPointsByID(m.Id, points[0], points[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hands, err := store.ListHands(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hands) != 3 {
+		t.Fatalf("want 3 hands recorded, got %d", len(hands))
+	}
+	for i, h := range hands {
+		if h.Number != i+1 {
+			t.Errorf("want hand %d to have number %d, got %d", i, i+1, h.Number)
+		}
+		if h.PlayedAt.IsZero() {
+			t.Errorf("want hand %d to have a timestamp", h.Number)
+		}
+	}
+	if hands[1].Points2 != 45 {
+		t.Errorf("want second hand to record 45 points for team 2, got %d", hands[1].Points2)
+	}
+
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Hands) != 3 {
+		t.Errorf("want match to be loaded with 3 hands, got %d", len(got.Hands))
+	}
+}
+
+func TestSQLiteStore_AddHandAssignsNextNumber(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hands, err := store.ListHands(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := hands[0]
+	next.Points1, next.Points2 = 0, 25
+	err = store.AddHand(&next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Number != 2 {
+		t.Errorf("want appended hand to be number 2, got %d", next.Number)
+	}
+}
+
+func TestSQLiteStore_AddPointsByIDFromConcurrentScorers(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const scorers = 20
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, scorers)
+	for i := 0; i < scorers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := store.AddPointsByID(m.Id, 1, 0)
+			errs <- err
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("want every hand scored, got %s", err)
+		}
+	}
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Score1 != scorers || len(got.Hands) != scorers {
+		t.Errorf("want %d points over %d hands, got %d points over %d hands", scorers, scorers, got.Score1, len(got.Hands))
+	}
+}
+
+func TestSQLiteStore_UndoLastHandReopensMatch(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 150, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	over, err := store.AddPointsByID(m.Id, 85, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !over.GameOver() {
+		t.Fatal("want match to be over at 235 points")
+	}
+
+	got, err := store.UndoLastHand(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Score1 != 150 {
+		t.Errorf("want score 150 after undo, got %d", got.Score1)
+	}
+	if got.GameOver() {
+		t.Error("want match to be reopened after undo")
+	}
+	if len(got.Hands) != 1 {
+		t.Errorf("want 1 hand left after undo, got %d", len(got.Hands))
+	}
+
+	_, err = store.AddPointsByID(m.Id, 58, 0)
+	if err != nil {
+		t.Errorf("want to be able to add points after undo, got %s", err)
+	}
+}
+
+func TestSQLiteStore_UndoLastHandErrorsWithoutHands(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.UndoLastHand(m.Id)
+	_, ok := err.(*dominocount.HandNotFoundError)
+	if !ok {
+		t.Errorf("want HandNotFoundError, got %v", err)
+	}
+}
+
+func TestSQLiteStore_UpdateHandRecomputesTotals(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, points := range [][2]int{{85, 0}, {0, 20}, {130, 0}} {
+		_, err = store.AddPointsByID(m.Id, points[0], points[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := store.UpdateHand(m.Id, 1, dominocount.NewHand(58, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Score1 != 188 {
+		t.Errorf("want team 1 total to be 188 after correction, got %d", got.Score1)
+	}
+	if got.GameOver() {
+		t.Error("want match to be reopened after correction")
+	}
+
+	hands, err := store.ListHands(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hands[0].Points1 != 58 {
+		t.Errorf("want hand 1 to be corrected to 58, got %d", hands[0].Points1)
+	}
+
+	_, err = store.UpdateHand(m.Id, 9, dominocount.NewHand(10, 0))
+	_, ok := err.(*dominocount.HandNotFoundError)
+	if !ok {
+		t.Errorf("want HandNotFoundError editing a missing hand, got %v", err)
+	}
+}
+
+func TestSQLiteStore_MatchTargetScoreIsPersisted(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch(dominocount.MatchWithTargetScore(150))
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.AddPointsByID(m.Id, 150, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 10, 0)
+	_, ok := err.(*dominocount.GameOverError)
+	if !ok {
+		t.Errorf("want GameOverError once 150 is reached, got %v", err)
+	}
+
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Target != 150 {
+		t.Errorf("want target 150, got %d", got.Target)
+	}
+}
+
+// newLegacyDB writes a database from before migrations, with only the first
+// match table holding one match per pair of scores, and returns its path.
+func newLegacyDB(t *testing.T, scores ...[2]int) string {
+	t.Helper()
+	path := t.TempDir() + t.Name() + ".db"
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE match(
+ID INTEGER NOT NULL PRIMARY KEY,
+team1name TEXT  NOT NULL DEFAULT 'Team1',
+team2name TEXT  NOT NULL DEFAULT 'Team2',
+team1Score INTEGER NOT NULL DEFAULT 0,
+team2Score INTEGER NOT NULL DEFAULT 0
+);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, score := range scores {
+		_, err = db.Exec(`INSERT INTO match(team1Score, team2Score) VALUES (?, ?);`, score[0], score[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestSQLiteStore_LegacyMatchesNeedAnIssuedEditSecret(t *testing.T) {
+	t.Parallel()
+	store, err := dominocount.OpenSQLiteStore(newLegacyDB(t, [2]int{30, 0}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, _, err := store.ListMatches(dominocount.MatchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("want the legacy match listed, got %d matches", len(matches))
+	}
+	legacy := matches[0]
+	err = store.CheckEditAccess(legacy.Id, "", 0)
+	if _, ok := err.(*dominocount.EditForbiddenError); !ok {
+		t.Fatalf("want EditForbiddenError editing a match without secret, got %v", err)
+	}
+
+	secrets, err := store.IssueEditSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 1 || secrets[legacy.Slug] == "" {
+		t.Fatalf("want a secret issued for the legacy match, got %v", secrets)
+	}
+	err = store.CheckEditAccess(legacy.Id, secrets[legacy.Slug], 0)
+	if err != nil {
+		t.Errorf("want the issued secret to open the match, got %s", err)
+	}
+	secrets, err = store.IssueEditSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 0 {
+		t.Errorf("want no new secrets once every match has one, got %v", secrets)
+	}
+}
+
+func TestSQLiteStore_LegacyMatchesGetTimestamps(t *testing.T) {
+	t.Parallel()
+	store, err := dominocount.OpenSQLiteStore(newLegacyDB(t, [2]int{200, 0}, [2]int{30, 0}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, _, err := store.ListMatches(dominocount.MatchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("want both legacy matches listed, got %d", len(matches))
+	}
+	for _, m := range matches {
+		if m.CreatedAt.IsZero() {
+			t.Errorf("want a creation time for legacy match %d", m.Id)
+		}
+		finished := m.Status == dominocount.StatusFinished
+		if finished != !m.FinishedAt.IsZero() {
+			t.Errorf("want a finish time only for the finished legacy match, got %+v", m)
+		}
+	}
+}
+
+func TestOpenSQLiteStoreIsIdempotent(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatalf("want reopening a migrated store to succeed, got %s", err)
+	}
+	got, err := reopened.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Id != m.Id {
+		t.Errorf("want match %d to survive reopening, got %d", m.Id, got.Id)
+	}
+}
+
+func TestSQLiteStore_MatchStatusAndTimestampsArePersisted(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != dominocount.StatusInProgress || got.CreatedAt.IsZero() {
+		t.Errorf("want new match in progress with a creation time, got %s at %v", got.Status, got.CreatedAt)
+	}
+
+	_, err = store.AddPointsByID(m.Id, 200, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != dominocount.StatusFinished || got.FinishedAt.IsZero() {
+		t.Errorf("want finished match with a finish time, got %s at %v", got.Status, got.FinishedAt)
+	}
+
+	got, err = store.UndoLastHand(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != dominocount.StatusInProgress || !got.FinishedAt.IsZero() {
+		t.Errorf("want undo to reopen the match, got %s at %v", got.Status, got.FinishedAt)
+	}
+}
+
+func TestSQLiteStore_AbandonMatchRejectsPoints(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.AbandonMatch(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 10, 0)
+	_, ok := err.(*dominocount.GameOverError)
+	if !ok {
+		t.Errorf("want GameOverError adding points to an abandoned match, got %v", err)
+	}
+}
+
+func TestSQLiteStore_MatchRuleSetIsPersisted(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch(dominocount.MatchWithRuleSet(dominocount.PuertoRicanRules))
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Rules.Name() != dominocount.PuertoRicanRules.Name() {
+		t.Errorf("want rule set %s, got %s", dominocount.PuertoRicanRules.Name(), got.Rules.Name())
+	}
+	if got.Target != 500 {
+		t.Errorf("want target 500, got %d", got.Target)
+	}
+}
+
+func TestSQLiteStore_HandBonusesAreRecorded(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := dominocount.NewHand(0, 40).WithBonus(dominocount.Team2, dominocount.BonusPaseCorrido)
+	_, err = store.AddHandByID(m.Id, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hands, err := store.ListHands(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hands[0].Points2 != 65 {
+		t.Errorf("want hand to record 65 points with the bonus, got %d", hands[0].Points2)
+	}
+	bonuses := hands[0].BonusesFor(dominocount.Team2)
+	if len(bonuses) != 1 || bonuses[0].Kind != dominocount.BonusPaseCorrido {
+		t.Fatalf("want pase corrido bonus recorded for team 2, got %+v", hands[0].Bonuses)
+	}
+
+	got, err := store.UndoLastHand(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Score2 != 0 {
+		t.Errorf("want undo to remove the bonus points too, got %d", got.Score2)
+	}
+}
+
+func TestSQLiteStore_UpdateHandRevaluesBonuses(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := dominocount.NewHand(30, 0).WithBonus(dominocount.Team1, dominocount.BonusCapicua)
+	_, err = store.AddHandByID(m.Id, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edit := dominocount.NewHand(30, 0).WithBonus(dominocount.Team2, dominocount.BonusSalida)
+	edit.Tranque = true
+	got, err := store.UpdateHand(m.Id, 1, edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Score1 != 30 || got.Score2 != 25 {
+		t.Errorf("want scores 30 and 25 after moving the bonus, got %d and %d", got.Score1, got.Score2)
+	}
+
+	hands, err := store.ListHands(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hands[0].Points1 != 30 || hands[0].Points2 != 25 || !hands[0].Tranque {
+		t.Errorf("want the edited hand at 30 and 25 in a tranque, got %+v", hands[0])
+	}
+	if len(hands[0].BonusesFor(dominocount.Team1)) != 0 {
+		t.Errorf("want the capicua taken back from team 1, got %+v", hands[0].Bonuses)
+	}
+	bonuses := hands[0].BonusesFor(dominocount.Team2)
+	if len(bonuses) != 1 || bonuses[0].Kind != dominocount.BonusSalida {
+		t.Errorf("want the salida recorded for team 2, got %+v", hands[0].Bonuses)
+	}
+}
+
+func TestSQLiteStore_CreateMatchRejectsPlayerInTwoSeats(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch(
+		dominocount.MatchWithPlayer(dominocount.SeatNorth, "Ana"),
+		dominocount.MatchWithPlayer(dominocount.SeatSouth, "ana"),
+	)
+	err = store.CreateMatch(&m)
+	if _, ok := err.(*dominocount.PlayersShareMatchError); !ok {
+		t.Fatalf("want PlayersShareMatchError seating Ana twice, got %v", err)
+	}
+	matches, _, err := store.ListMatches(dominocount.MatchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("want no match created, got %d", len(matches))
+	}
+}
+
+func TestSQLiteStore_MatchPlayersAndHandWinnerArePersisted(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch(
+		dominocount.MatchWithPlayer(dominocount.SeatNorth, "Ana"),
+		dominocount.MatchWithPlayer(dominocount.SeatSouth, "Juan"),
+		dominocount.MatchWithPlayer(dominocount.SeatEast, "Luis"),
+		dominocount.MatchWithPlayer(dominocount.SeatWest, "Rosa"),
+	)
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddHandByID(m.Id, dominocount.NewHand(0, 30).WonBy(dominocount.SeatWest))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Players) != 4 || got.PlayerName(dominocount.SeatWest) != "Rosa" {
+		t.Errorf("want 4 players with Rosa sitting west, got %+v", got.Players)
+	}
+	if got.Hands[0].Domino != dominocount.SeatWest {
+		t.Errorf("want hand won by the west seat, got %q", got.Hands[0].Domino)
+	}
+}
+
+func TestSQLiteStore_MatchesReuseRegisteredPlayers(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := dominocount.NewMatch(dominocount.MatchWithPlayer(dominocount.SeatNorth, "Ana"))
+	err = store.CreateMatch(&first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := dominocount.NewMatch(dominocount.MatchWithPlayer(dominocount.SeatEast, " ana "))
+	err = store.CreateMatch(&second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Players[0].ID != second.Players[0].ID {
+		t.Errorf("want both matches to reference the same player, got %d and %d", first.Players[0].ID, second.Players[0].ID)
+	}
+	players, err := store.ListPlayers("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 1 {
+		t.Errorf("want a single registered player, got %+v", players)
+	}
+}
+
+func TestSQLiteStore_CreateListAndRenamePlayers(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Rosa", "Ramón", "Luis"} {
+		_, err = store.CreatePlayer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = store.CreatePlayer("rosa")
+	_, ok := err.(*dominocount.PlayerExistsError)
+	if !ok {
+		t.Errorf("want PlayerExistsError registering a taken name, got %v", err)
+	}
+
+	players, err := store.ListPlayers("r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 2 || players[0].Name != "Ramón" {
+		t.Fatalf("want Ramón and Rosa listed for prefix r, got %+v", players)
+	}
+
+	m := dominocount.NewMatch(dominocount.MatchWithPlayer(dominocount.SeatNorth, "Rosa"))
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.RenamePlayer(players[1].ID, "Rosa María")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PlayerName(dominocount.SeatNorth) != "Rosa María" {
+		t.Errorf("want match to show the new name, got %s", got.PlayerName(dominocount.SeatNorth))
+	}
+
+	err = store.RenamePlayer(999, "nadie")
+	_, ok = err.(*dominocount.PlayerNotFoundError)
+	if !ok {
+		t.Errorf("want PlayerNotFoundError renaming a missing player, got %v", err)
+	}
+}
+
+func TestSQLiteStore_MergePlayersMovesMatches(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch(dominocount.MatchWithPlayer(dominocount.SeatNorth, "Jose"))
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := dominocount.NewMatch(dominocount.MatchWithPlayer(dominocount.SeatEast, "José"))
+	err = store.CreateMatch(&other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typo, right := m.Players[0].ID, other.Players[0].ID
+
+	err = store.MergePlayers(typo, right)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PlayerName(dominocount.SeatNorth) != "José" {
+		t.Errorf("want merged seat to point to José, got %s", got.PlayerName(dominocount.SeatNorth))
+	}
+	players, err := store.ListPlayers("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 1 {
+		t.Errorf("want merged player to be removed, got %+v", players)
+	}
+}
+
+func TestSQLiteStore_MergePlayersRejectsSharedMatch(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch(
+		dominocount.MatchWithPlayer(dominocount.SeatNorth, "Jose"),
+		dominocount.MatchWithPlayer(dominocount.SeatEast, "José"),
+	)
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 200, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.MergePlayers(m.Players[0].ID, m.Players[1].ID)
+	if _, ok := err.(*dominocount.PlayersShareMatchError); !ok {
+		t.Fatalf("want PlayersShareMatchError merging players of the same match, got %v", err)
+	}
+	players, err := store.ListPlayers("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 2 {
+		t.Errorf("want both players kept after the rejected merge, got %+v", players)
+	}
+}
+
+func TestSQLiteStore_ListMatchesFiltersAndPaginates(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		m := dominocount.NewMatch(dominocount.MatchWithTeam1Name("foo"), dominocount.MatchWithPlayer(dominocount.SeatNorth, "Ana"))
+		err = store.CreateMatch(&m)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	other := dominocount.NewMatch(dominocount.MatchWithTeam1Name("bar"))
+	err = store.CreateMatch(&other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(other.Id, 200, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, next, err := store.ListMatches(dominocount.MatchFilter{Team: "foo", Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 3 || next == 0 {
+		t.Fatalf("want a first page of 3 foo matches and a cursor, got %d and %d", len(page), next)
+	}
+	rest, next, err := store.ListMatches(dominocount.MatchFilter{Team: "foo", Limit: 3, Cursor: next})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 2 || next != 0 {
+		t.Errorf("want a last page of 2 foo matches, got %d and cursor %d", len(rest), next)
+	}
+	if rest[0].Id >= page[2].Id {
+		t.Errorf("want matches listed newest first across pages")
+	}
+
+	byPlayer, _, err := store.ListMatches(dominocount.MatchFilter{Player: "ana"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byPlayer) != 5 {
+		t.Errorf("want 5 matches played by Ana, got %d", len(byPlayer))
+	}
+
+	finished, _, err := store.ListMatches(dominocount.MatchFilter{Status: dominocount.StatusFinished})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(finished) != 1 || finished[0].Id != other.Id {
+		t.Errorf("want only the finished match listed, got %+v", finished)
+	}
+
+	future, _, err := store.ListMatches(dominocount.MatchFilter{From: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(future) != 0 {
+		t.Errorf("want no matches created in the future, got %d", len(future))
+	}
+}
+
+func TestSQLiteStore_DeleteMatchArchivesAndRestores(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.DeleteMatch(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active, _, err := store.ListMatches(dominocount.MatchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 0 {
+		t.Errorf("want archived match to be hidden from the listing, got %d matches", len(active))
+	}
+	archived, _, err := store.ListMatches(dominocount.MatchFilter{Archived: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archived) != 1 || !archived[0].Archived() {
+		t.Errorf("want the match listed as archived, got %+v", archived)
+	}
+	_, err = store.AddPointsByID(m.Id, 10, 0)
+	_, ok := err.(*dominocount.MatchArchivedError)
+	if !ok {
+		t.Errorf("want MatchArchivedError adding points to an archived match, got %v", err)
+	}
+	_, err = store.UndoLastHand(m.Id)
+	_, ok = err.(*dominocount.MatchArchivedError)
+	if !ok {
+		t.Errorf("want MatchArchivedError undoing a hand of an archived match, got %v", err)
+	}
+	_, err = store.UpdateHand(m.Id, 1, dominocount.NewHand(0, 20))
+	_, ok = err.(*dominocount.MatchArchivedError)
+	if !ok {
+		t.Errorf("want MatchArchivedError editing a hand of an archived match, got %v", err)
+	}
+
+	err = store.RestoreMatch(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Archived() {
+		t.Error("want match to be restored")
+	}
+
+	err = store.DeleteMatch(999)
+	_, ok = err.(*dominocount.MatchNotFoundError)
+	if !ok {
+		t.Errorf("want MatchNotFoundError deleting a missing match, got %v", err)
+	}
+}
+
+func TestSQLiteStore_GetMatchByWatchToken(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := dominocount.NewMatch()
+	err = store.CreateMatch(&first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := dominocount.NewMatch()
+	err = store.CreateMatch(&second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.WatchToken) < 32 || first.WatchToken == second.WatchToken {
+		t.Fatalf("want a distinct unguessable token per match, got %q and %q", first.WatchToken, second.WatchToken)
+	}
+
+	got, err := store.GetMatchByWatchToken(second.WatchToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Id != second.Id {
+		t.Errorf("want match %d for its token, got %d", second.Id, got.Id)
+	}
+
+	got, err = store.GetMatchByWatchToken("nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Id != 0 {
+		t.Errorf("want no match for an unknown token, got %d", got.Id)
+	}
+}
+
+func TestSQLiteStore_CheckEditAccess(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Slug == "" || m.EditSecret == "" {
+		t.Fatalf("want slug and edit secret assigned on creation, got %q and %q", m.Slug, m.EditSecret)
+	}
+
+	id, err := store.GetMatchIDBySlug(m.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != m.Id {
+		t.Errorf("want match %d for its slug, got %d", m.Id, id)
+	}
+	_, err = store.GetMatchIDBySlug("nope")
+	_, ok := err.(*dominocount.MatchNotFoundError)
+	if !ok {
+		t.Errorf("want MatchNotFoundError for an unknown slug, got %v", err)
+	}
+
+	err = store.CheckEditAccess(m.Id, m.EditSecret, 0)
+	if err != nil {
+		t.Errorf("want the edit secret accepted, got %v", err)
+	}
+	for _, secret := range []string{"", "nope", m.WatchToken} {
+		err = store.CheckEditAccess(m.Id, secret, 0)
+		_, ok = err.(*dominocount.EditForbiddenError)
+		if !ok {
+			t.Errorf("want EditForbiddenError for secret %q, got %v", secret, err)
+		}
+	}
+}
+
+func TestSQLiteStore_UsersAndSessions(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := store.CreateUser(" Ana ", "secreta123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID == 0 || u.Name != "Ana" {
+		t.Fatalf("want user Ana created, got %+v", u)
+	}
+	_, err = store.CreateUser("ana", "otraclave1")
+	_, ok := err.(*dominocount.UserExistsError)
+	if !ok {
+		t.Errorf("want UserExistsError for a taken name, got %v", err)
+	}
+	_, err = store.CreateUser("Luis", "corta")
+	if err == nil {
+		t.Error("want error for a short password")
+	}
+	_, err = store.CreateUser("Luis", strings.Repeat("a", dominocount.MaxPasswordLength+1))
+	_, ok = err.(*dominocount.PasswordTooLongError)
+	if !ok {
+		t.Errorf("want PasswordTooLongError for a password bcrypt cannot hash, got %v", err)
+	}
+
+	_, err = store.AuthenticateUser("Ana", "equivocada")
+	_, ok = err.(*dominocount.InvalidCredentialsError)
+	if !ok {
+		t.Errorf("want InvalidCredentialsError for a wrong password, got %v", err)
+	}
+	got, err := store.AuthenticateUser("ana", "secreta123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != u.ID {
+		t.Errorf("want user %d authenticated, got %d", u.ID, got.ID)
+	}
+
+	token, err := store.CreateSession(u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetUserBySession(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != u.ID {
+		t.Errorf("want session of user %d, got %d", u.ID, got.ID)
+	}
+	err = store.DeleteSession(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetUserBySession(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 0 {
+		t.Errorf("want no user after logout, got %d", got.ID)
+	}
+}
+
+func TestSQLiteStore_OwnerCanEditAndListMatches(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, err := store.CreateUser("Ana", "secreta123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := store.CreateUser("Luis", "secreta123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch(dominocount.MatchWithOwner(owner.ID))
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anonymous := dominocount.NewMatch()
+	err = store.CreateMatch(&anonymous)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.CheckEditAccess(m.Id, "", owner.ID)
+	if err != nil {
+		t.Errorf("want the owner allowed to edit, got %v", err)
+	}
+	err = store.CheckEditAccess(m.Id, "", other.ID)
+	_, ok := err.(*dominocount.EditForbiddenError)
+	if !ok {
+		t.Errorf("want other users forbidden, got %v", err)
+	}
+
+	mine, _, err := store.ListMatches(dominocount.MatchFilter{Owner: owner.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mine) != 1 || mine[0].Id != m.Id || mine[0].OwnerID != owner.ID {
+		t.Errorf("want only the owned match listed, got %+v", mine)
+	}
+}
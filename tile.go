@@ -0,0 +1,83 @@
+package dominocount
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tile is a domino piece. Tiles are kept normalized with A <= B so 5-3 and 3-5
+// are the same tile.
+type tile struct {
+	A, B int
+}
+
+func newTile(a, b int) tile {
+	if a > b {
+		a, b = b, a
+	}
+	return tile{A: a, B: b}
+}
+
+// Pips is the points the tile counts for at the end of a hand.
+func (t tile) Pips() int {
+	return t.A + t.B
+}
+
+func (t tile) String() string {
+	return fmt.Sprintf("%d-%d", t.A, t.B)
+}
+
+// Tiles returns the full set of tiles up to the double of maxPip, 28 tiles
+// for a double-six set and 55 for a double-nine set.
+func Tiles(maxPip int) []tile {
+	tiles := []tile{}
+	for a := 0; a <= maxPip; a++ {
+		for b := a; b <= maxPip; b++ {
+			tiles = append(tiles, tile{A: a, B: b})
+		}
+	}
+	return tiles
+}
+
+// parseTile parses a tile written as "a-b" and checks it belongs to the set
+// up to the double of maxPip.
+func parseTile(s string, maxPip int) (tile, error) {
+	a, b, found := strings.Cut(s, "-")
+	if !found {
+		return tile{}, errors.New("not able to parse tile " + s)
+	}
+	pipsA, err := strconv.Atoi(a)
+	if err != nil {
+		return tile{}, errors.New("not able to parse tile " + s)
+	}
+	pipsB, err := strconv.Atoi(b)
+	if err != nil {
+		return tile{}, errors.New("not able to parse tile " + s)
+	}
+	if pipsA < 0 || pipsB < 0 || pipsA > maxPip || pipsB > maxPip {
+		return tile{}, errors.New("tile " + s + " is not in the set")
+	}
+	return newTile(pipsA, pipsB), nil
+}
+
+// CountTiles returns the pips of the leftover tiles written as "a-b". Every
+// tile exists once in a set so a tile repeated among the players' hands is
+// an error.
+func CountTiles(tiles []string, maxPip int) (int, error) {
+	seen := map[tile]bool{}
+	total := 0
+	for _, s := range tiles {
+		t, err := parseTile(s, maxPip)
+		if err != nil {
+			return 0, err
+		}
+		if seen[t] {
+			return 0, errors.New("tile " + t.String() + " used more than once")
+		}
+		seen[t] = true
+		total += t.Pips()
+	}
+	return total, nil
+}
@@ -0,0 +1,48 @@
+package dominocount_test
+
+import (
+	"dominocount"
+	"testing"
+)
+
+func TestTilesReturnsFullSet(t *testing.T) {
+	t.Parallel()
+	testCases := map[int]int{6: 28, 9: 55}
+	for maxPip, want := range testCases {
+		got := len(dominocount.Tiles(maxPip))
+		if got != want {
+			t.Errorf("want double-%d set to have %d tiles, got %d", maxPip, want, got)
+		}
+	}
+}
+
+func TestCountTilesSumsPips(t *testing.T) {
+	t.Parallel()
+	got, err := dominocount.CountTiles([]string{"6-6", "3-5", "0-1"}, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 21 {
+		t.Errorf("want 21 pips, got %d", got)
+	}
+}
+
+func TestCountTilesRejectsInvalidTiles(t *testing.T) {
+	t.Parallel()
+	testCases := map[string][]string{
+		"repeated tile":        {"3-5", "5-3"},
+		"tile outside the set": {"6-7"},
+		"malformed tile":       {"35"},
+	}
+	for name, tiles := range testCases {
+		_, err := dominocount.CountTiles(tiles, 6)
+		if err == nil {
+			t.Errorf("%s: want error counting %v", name, tiles)
+		}
+	}
+
+	_, err := dominocount.CountTiles([]string{"7-9"}, 9)
+	if err != nil {
+		t.Errorf("want double-nine tiles to be valid in a double-nine set, got %s", err)
+	}
+}
@@ -0,0 +1,397 @@
+package dominocount
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tournamentFormat is how the teams of a tournament are paired.
+type tournamentFormat string
+
+const (
+	// FormatElimination is a single-elimination bracket, the loser of each
+	// game is out and the winner moves on to the next round.
+	FormatElimination tournamentFormat = "eliminacion"
+	// FormatRoundRobin pairs every team with every other team once.
+	FormatRoundRobin tournamentFormat = "todos_contra_todos"
+)
+
+var allTournamentFormats = []tournamentFormat{FormatElimination, FormatRoundRobin}
+
+// TournamentFormats returns the formats a tournament can be played in.
+func TournamentFormats() []tournamentFormat {
+	return allTournamentFormats
+}
+
+func parseTournamentFormat(s string) (tournamentFormat, error) {
+	for _, f := range allTournamentFormats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", errors.New("unknown tournament format " + s)
+}
+
+// Label returns the format as shown to players.
+func (f tournamentFormat) Label() string {
+	switch f {
+	case FormatElimination:
+		return "eliminación directa"
+	case FormatRoundRobin:
+		return "todos contra todos"
+	}
+	return string(f)
+}
+
+// tournament groups the matches of a club tournament. Its games are created
+// from the registered teams and each game is scored as a regular match.
+type tournament struct {
+	ID        int64            `json:"-"`
+	Slug      string           `json:"id"`
+	Name      string           `json:"name"`
+	Format    tournamentFormat `json:"format"`
+	Rules     RuleSet          `json:"rule_set"`
+	Target    int              `json:"target_score"`
+	Teams     []string         `json:"teams"`
+	Games     []tournamentGame `json:"games"`
+	CreatedAt time.Time        `json:"created_at"`
+	OwnerID   int64            `json:"-"`
+	// EditSecret works like the edit secret of a match, for the tournament
+	// and all of its matches.
+	EditSecret string `json:"-"`
+}
+
+// tournamentGame is a pairing of the tournament. Teams are empty until the
+// games feeding it are decided, and Match is nil until both teams are known.
+type tournamentGame struct {
+	Round  int    `json:"round"`
+	Slot   int    `json:"slot"`
+	Team1  string `json:"team1"`
+	Team2  string `json:"team2"`
+	Winner team   `json:"winner,omitempty"`
+	Match  *match `json:"match,omitempty"`
+	// MatchID links the game to its match, loaded or not.
+	MatchID int64 `json:"-"`
+}
+
+type tournamentOption func(*tournament) error
+
+func NewTournament(opts ...tournamentOption) tournament {
+	t := tournament{
+		Format:    FormatElimination,
+		Rules:     DominicanRules,
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, opt := range opts {
+		//ignoring errors as current options generate no errors
+		opt(&t)
+	}
+	if t.Target == 0 {
+		t.Target = t.Rules.TargetScore()
+	}
+	return t
+}
+
+func TournamentWithName(name string) tournamentOption {
+	return func(t *tournament) error {
+		t.Name = strings.TrimSpace(name)
+		return nil
+	}
+}
+
+func TournamentWithFormat(f tournamentFormat) tournamentOption {
+	return func(t *tournament) error {
+		if f != "" {
+			t.Format = f
+		}
+		return nil
+	}
+}
+
+// TournamentWithTeams registers the teams in seed order, best first. Blank
+// names are skipped.
+func TournamentWithTeams(teams ...string) tournamentOption {
+	return func(t *tournament) error {
+		for _, name := range teams {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				t.Teams = append(t.Teams, name)
+			}
+		}
+		return nil
+	}
+}
+
+// TournamentWithRuleSet sets the variant every match of the tournament is
+// scored with. A nil rule set keeps the default dominican rules.
+func TournamentWithRuleSet(r RuleSet) tournamentOption {
+	return func(t *tournament) error {
+		if r != nil {
+			t.Rules = r
+		}
+		return nil
+	}
+}
+
+// TournamentWithTargetScore sets the target of every match. Values below 1
+// keep the target of the rule set.
+func TournamentWithTargetScore(target int) tournamentOption {
+	return func(t *tournament) error {
+		if target > 0 {
+			t.Target = target
+		}
+		return nil
+	}
+}
+
+func TournamentWithOwner(userID int64) tournamentOption {
+	return func(t *tournament) error {
+		t.OwnerID = userID
+		return nil
+	}
+}
+
+// validate checks the tournament can be played.
+func (t tournament) validate() error {
+	if t.Name == "" {
+		return errors.New("tournament name cannot be empty")
+	}
+	if len(t.Teams) < 2 {
+		return errors.New("a tournament needs at least two teams")
+	}
+	seen := map[string]bool{}
+	for _, name := range t.Teams {
+		key := strings.ToLower(name)
+		if seen[key] {
+			return errors.New("team " + name + " is registered twice")
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// schedule returns the games of the tournament before any is played. The
+// first round of an elimination bracket pairs the seeds so the best teams
+// meet last, with byes for the top seeds when the teams are not a power of
+// two. A round robin uses the circle method, one round per team.
+func (t tournament) schedule() []tournamentGame {
+	if t.Format == FormatRoundRobin {
+		return roundRobin(t.Teams)
+	}
+	return eliminationBracket(t.Teams)
+}
+
+func eliminationBracket(teams []string) []tournamentGame {
+	size := 1
+	for size < len(teams) {
+		size *= 2
+	}
+	seed := func(n int) string {
+		if n > len(teams) {
+			return ""
+		}
+		return teams[n-1]
+	}
+
+	games := []tournamentGame{}
+	order := bracketOrder(size)
+	for slot := 0; slot < size/2; slot++ {
+		games = append(games, tournamentGame{
+			Round: 1,
+			Slot:  slot,
+			Team1: seed(order[2*slot]),
+			Team2: seed(order[2*slot+1]),
+		})
+	}
+	for round, n := 2, size/4; n >= 1; round, n = round+1, n/2 {
+		for slot := 0; slot < n; slot++ {
+			games = append(games, tournamentGame{Round: round, Slot: slot})
+		}
+	}
+	return games
+}
+
+// bracketOrder returns the seeds 1..size in bracket position order, so that
+// seeds 1 and 2 can only meet in the final.
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for n := 2; n <= size; n *= 2 {
+		next := make([]int, 0, n)
+		for _, s := range order {
+			next = append(next, s, n+1-s)
+		}
+		order = next
+	}
+	return order
+}
+
+func roundRobin(teams []string) []tournamentGame {
+	circle := append([]string{}, teams...)
+	if len(circle)%2 == 1 {
+		circle = append(circle, "")
+	}
+	n := len(circle)
+
+	games := []tournamentGame{}
+	for round := 1; round < n; round++ {
+		slot := 0
+		for i := 0; i < n/2; i++ {
+			team1, team2 := circle[i], circle[n-1-i]
+			if team1 == "" || team2 == "" {
+				continue
+			}
+			games = append(games, tournamentGame{Round: round, Slot: slot, Team1: team1, Team2: team2})
+			slot++
+		}
+		// keep the first team in place and rotate the others
+		circle = append([]string{circle[0], circle[n-1]}, circle[1:n-1]...)
+	}
+	return games
+}
+
+// settle brings the games in line with the recorded winners. In an
+// elimination bracket a team without rival in the first round wins by bye and
+// each winner takes its place in the next round. Teams of later rounds are
+// worked out again from scratch, so clearing a winner takes its team back.
+func (t *tournament) settle() {
+	if t.Format != FormatElimination {
+		return
+	}
+	for i := range t.Games {
+		g := &t.Games[i]
+		if g.Round > 1 {
+			g.Team1, g.Team2 = "", ""
+			continue
+		}
+		if g.Team2 == "" {
+			g.Winner = Team1
+		}
+	}
+	for _, g := range t.Games {
+		name := g.WinnerName()
+		if name == "" {
+			continue
+		}
+		next := t.game(g.Round+1, g.Slot/2)
+		if next == nil {
+			continue
+		}
+		if g.Slot%2 == 0 {
+			next.Team1 = name
+		} else {
+			next.Team2 = name
+		}
+	}
+}
+
+// game returns the game at round and slot, nil if there is none.
+func (t *tournament) game(round, slot int) *tournamentGame {
+	for i := range t.Games {
+		if t.Games[i].Round == round && t.Games[i].Slot == slot {
+			return &t.Games[i]
+		}
+	}
+	return nil
+}
+
+// Ready reports whether both teams of the game are known.
+func (g tournamentGame) Ready() bool {
+	return g.Team1 != "" && g.Team2 != ""
+}
+
+// Bye reports whether the game was won without playing.
+func (g tournamentGame) Bye() bool {
+	return g.Winner != "" && !g.Ready()
+}
+
+// WinnerName returns the name of the team that won the game, empty while it
+// is undecided.
+func (g tournamentGame) WinnerName() string {
+	switch g.Winner {
+	case Team1:
+		return g.Team1
+	case Team2:
+		return g.Team2
+	}
+	return ""
+}
+
+// Rounds returns the games grouped by round, in order.
+func (t tournament) Rounds() [][]tournamentGame {
+	rounds := [][]tournamentGame{}
+	for _, g := range t.Games {
+		for len(rounds) < g.Round {
+			rounds = append(rounds, []tournamentGame{})
+		}
+		rounds[g.Round-1] = append(rounds[g.Round-1], g)
+	}
+	return rounds
+}
+
+// Champion returns the winner of the tournament, empty while it is being
+// played. In a round robin it is the leader once every game is decided.
+func (t tournament) Champion() string {
+	if len(t.Games) == 0 {
+		return ""
+	}
+	if t.Format == FormatElimination {
+		return t.Games[len(t.Games)-1].WinnerName()
+	}
+	for _, g := range t.Games {
+		if g.Winner == "" {
+			return ""
+		}
+	}
+	return t.Standings()[0].Team
+}
+
+// standing is the record of a team in a round robin.
+type standing struct {
+	Team          string
+	Played        int
+	Won           int
+	PointsFor     int
+	PointsAgainst int
+}
+
+// Standings ranks the teams by games won, then by point difference. Points
+// come from the matches loaded with the games.
+func (t tournament) Standings() []standing {
+	byTeam := map[string]*standing{}
+	standings := make([]standing, len(t.Teams))
+	for i, name := range t.Teams {
+		standings[i].Team = name
+		byTeam[name] = &standings[i]
+	}
+	for _, g := range t.Games {
+		if g.Winner == "" || !g.Ready() {
+			continue
+		}
+		team1, team2 := byTeam[g.Team1], byTeam[g.Team2]
+		if team1 == nil || team2 == nil {
+			continue
+		}
+		team1.Played++
+		team2.Played++
+		if g.Winner == Team1 {
+			team1.Won++
+		} else {
+			team2.Won++
+		}
+		if g.Match != nil {
+			team1.PointsFor += g.Match.Score1
+			team1.PointsAgainst += g.Match.Score2
+			team2.PointsFor += g.Match.Score2
+			team2.PointsAgainst += g.Match.Score1
+		}
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		if standings[i].Won != standings[j].Won {
+			return standings[i].Won > standings[j].Won
+		}
+		return standings[i].PointsFor-standings[i].PointsAgainst > standings[j].PointsFor-standings[j].PointsAgainst
+	})
+	return standings
+}
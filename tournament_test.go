@@ -0,0 +1,326 @@
+package dominocount_test
+
+import (
+	"dominocount"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"testing"
+)
+
+func TestSQLiteStore_CreateTournamentValidatesTeams(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, teams := range [][]string{
+		{},
+		{"solo"},
+		{"Los Primos", " ", "los primos"},
+	} {
+		tr := dominocount.NewTournament(
+			dominocount.TournamentWithName("Copa"),
+			dominocount.TournamentWithTeams(teams...),
+		)
+		err = store.CreateTournament(&tr)
+		if err == nil {
+			t.Errorf("want an error creating a tournament with teams %q", teams)
+		}
+	}
+	tr := dominocount.NewTournament(dominocount.TournamentWithTeams("a", "b"))
+	err = store.CreateTournament(&tr)
+	if err == nil {
+		t.Error("want an error creating a tournament without name")
+	}
+}
+
+func TestSQLiteStore_EliminationTournamentAdvancesWinners(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := dominocount.NewTournament(
+		dominocount.TournamentWithName("Copa del Barrio"),
+		dominocount.TournamentWithTeams("A", "B", "C", "D", "E"),
+	)
+	err = store.CreateTournament(&tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Slug == "" || tr.EditSecret == "" {
+		t.Fatal("want a slug and an edit secret for the new tournament")
+	}
+
+	got, err := store.GetTournamentBySlug(tr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rounds := got.Rounds()
+	if len(rounds) != 3 || len(rounds[0]) != 4 || len(rounds[1]) != 2 || len(rounds[2]) != 1 {
+		t.Fatalf("want rounds of 4, 2 and 1 games for 5 teams, got %+v", rounds)
+	}
+	byes := 0
+	for _, g := range rounds[0] {
+		if g.Bye() {
+			byes++
+		}
+	}
+	if byes != 3 {
+		t.Errorf("want the top 3 seeds to pass by bye, got %d byes", byes)
+	}
+	playIn := rounds[0][1]
+	if playIn.Team1 != "D" || playIn.Team2 != "E" || playIn.Match == nil {
+		t.Fatalf("want D against E in a match, got %+v", playIn)
+	}
+	semi := rounds[1][1]
+	if semi.Team1 != "B" || semi.Team2 != "C" || semi.Match == nil {
+		t.Fatalf("want B against C in a match after the byes, got %+v", semi)
+	}
+	if rounds[1][0].Match != nil {
+		t.Fatal("want no match until the rival of A is known")
+	}
+	err = store.CheckEditAccess(playIn.Match.Id, tr.EditSecret, 0)
+	if err != nil {
+		t.Errorf("want the tournament secret to open its matches, got %s", err)
+	}
+
+	_, err = store.AddHandByID(playIn.Match.Id, dominocount.NewHand(0, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetTournamentBySlug(tr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	semi = got.Rounds()[1][0]
+	if semi.Team1 != "A" || semi.Team2 != "E" || semi.Match == nil {
+		t.Fatalf("want E to move on to play A, got %+v", semi)
+	}
+
+	_, err = store.UndoLastHand(playIn.Match.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetTournamentBySlug(tr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	semi = got.Rounds()[1][0]
+	if semi.Team2 != "" || semi.Match != nil {
+		t.Fatalf("want E taken back after the undo, got %+v", semi)
+	}
+
+	_, err = store.AddHandByID(playIn.Match.Id, dominocount.NewHand(200, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetTournamentBySlug(tr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	semi = got.Rounds()[1][0]
+	if semi.Team2 != "D" || semi.Match == nil {
+		t.Fatalf("want D to move on after winning the replay, got %+v", semi)
+	}
+	_, err = store.AddHandByID(semi.Match.Id, dominocount.NewHand(30, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.UndoLastHand(playIn.Match.Id)
+	if _, ok := err.(*dominocount.TournamentAdvancedError); !ok {
+		t.Fatalf("want TournamentAdvancedError once the next round started, got %v", err)
+	}
+}
+
+func TestSQLiteStore_EliminationTournamentReplacesStaleMatches(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := dominocount.NewTournament(
+		dominocount.TournamentWithName("Copa del Barrio"),
+		dominocount.TournamentWithTeams("A", "B", "C", "D"),
+	)
+	err = store.CreateTournament(&tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.GetTournamentBySlug(tr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := got.Rounds()[0][0], got.Rounds()[0][1]
+	if first.Team1 != "A" || first.Team2 != "D" || second.Team1 != "B" || second.Team2 != "C" {
+		t.Fatalf("want A against D and B against C, got %+v and %+v", first, second)
+	}
+	_, err = store.AddHandByID(first.Match.Id, dominocount.NewHand(200, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddHandByID(second.Match.Id, dominocount.NewHand(200, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.UpdateHand(first.Match.Id, 1, dominocount.NewHand(0, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetTournamentBySlug(tr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	final := got.Rounds()[1][0]
+	if final.Team1 != "D" || final.Team2 != "B" || final.Match == nil {
+		t.Fatalf("want D to play B in the final after the edit, got %+v", final)
+	}
+	if final.Match.Team1 != "D" || final.Match.Team2 != "B" {
+		t.Errorf("want the final match between D and B, got %s against %s", final.Match.Team1, final.Match.Team2)
+	}
+
+	abandoned := final.Match.Id
+	_, err = store.AddHandByID(abandoned, dominocount.NewHand(30, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AbandonMatch(abandoned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetTournamentBySlug(tr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	final = got.Rounds()[1][0]
+	if final.Match == nil || final.Match.Id == abandoned || final.Match.Team1 != "D" || final.Match.Team2 != "B" {
+		t.Fatalf("want a new match to replay the abandoned final, got %+v", final)
+	}
+	_, err = store.AddHandByID(final.Match.Id, dominocount.NewHand(0, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetTournamentBySlug(tr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Rounds()[1][0].WinnerName() != "B" {
+		t.Errorf("want B to win the replayed final, got %+v", got.Rounds()[1][0])
+	}
+
+	_, err = store.UpdateHand(second.Match.Id, 1, dominocount.NewHand(0, 200))
+	if _, ok := err.(*dominocount.TournamentAdvancedError); !ok {
+		t.Errorf("want TournamentAdvancedError changing a winner that already played on, got %v", err)
+	}
+}
+
+func TestSQLiteStore_RoundRobinTournamentStandings(t *testing.T) {
+	t.Parallel()
+	tempDB := t.TempDir() + t.Name()
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := dominocount.NewTournament(
+		dominocount.TournamentWithName("Liguita"),
+		dominocount.TournamentWithFormat(dominocount.FormatRoundRobin),
+		dominocount.TournamentWithTeams("A", "B", "C"),
+		dominocount.TournamentWithTargetScore(100),
+	)
+	err = store.CreateTournament(&tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.GetTournamentBySlug(tr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Games) != 3 {
+		t.Fatalf("want every pair of 3 teams to play once, got %d games", len(got.Games))
+	}
+	for _, g := range got.Games {
+		if g.Match == nil || g.Match.Target != 100 {
+			t.Fatalf("want a match to 100 for every game, got %+v", g)
+		}
+		points1, points2 := 100, 0
+		if g.Team1 == "C" || g.Team2 == "A" {
+			points1, points2 = 0, 100
+		}
+		_, err = store.AddHandByID(g.Match.Id, dominocount.NewHand(points1, points2))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err = store.GetTournamentBySlug(tr.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	standings := got.Standings()
+	if standings[0].Team != "A" || standings[0].Won != 2 || standings[2].Team != "C" || standings[2].Won != 0 {
+		t.Errorf("want A first and C last, got %+v", standings)
+	}
+	if got.Champion() != "A" {
+		t.Errorf("want A champion, got %q", got.Champion())
+	}
+}
+
+func TestTournamentPageLinksEditorsToScoring(t *testing.T) {
+	t.Parallel()
+	client, testServer, store := newAccountTestClient(t)
+
+	res, err := client.PostForm(testServer.URL+"/tournament/", map[string][]string{
+		"name":   {"Copa"},
+		"format": {"eliminacion"},
+		"teams":  {"Los Primos\r\nLa Esquina\r\n"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || !strings.Contains(string(body), "anotar") {
+		t.Fatalf("want the creator to score the final, got %d\n%s", res.StatusCode, body)
+	}
+	slug := strings.TrimPrefix(res.Request.URL.Path, "/tournament/")
+	tr, err := store.GetTournamentBySlug(slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Name != "Copa" || len(tr.Games) != 1 || tr.Games[0].Team1 != "Los Primos" {
+		t.Fatalf("want the final between the two teams, got %+v", tr)
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = (&http.Client{Jar: jar}).Get(testServer.URL + "/tournament/" + slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(body)
+	if strings.Contains(got, "anotar") || !strings.Contains(got, "/watch/"+tr.Games[0].Match.WatchToken) {
+		t.Errorf("want only the spectator link for visitors\nGot:\n%s", got)
+	}
+
+	res, err = client.Get(testServer.URL + "/tournament/nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("want status 404 for an unknown tournament, got %d", res.StatusCode)
+	}
+}
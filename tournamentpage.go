@@ -0,0 +1,167 @@
+package dominocount
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// tournamentForm is the data of the create tournament form.
+type tournamentForm struct {
+	Name     string
+	Teams    string
+	Formats  []tournamentFormat
+	RuleSets []RuleSet
+	Error    string
+}
+
+func newTournamentForm() tournamentForm {
+	return tournamentForm{Formats: TournamentFormats(), RuleSets: RuleSets()}
+}
+
+// bracketPage is the data of the tournament page. Editable is set for the
+// owner and for visitors holding the tournament secret, which the match links
+// of the bracket pass on as EditSecret.
+type bracketPage struct {
+	*tournament
+	Editable   bool   `json:"-"`
+	EditSecret string `json:"-"`
+}
+
+// MatchURL returns where the bracket links the match to.
+func (p bracketPage) MatchURL(m *match) string {
+	return matchLink(*m, p.Editable, p.EditSecret)
+}
+
+// matchLink links editors to the scorekeeper page of m, passing on the edit
+// secret they came with, and everyone else to its spectator view.
+func matchLink(m match, editable bool, secret string) string {
+	if !editable {
+		return "/watch/" + m.WatchToken
+	}
+	if secret == "" {
+		return "/match/" + m.Slug
+	}
+	return "/match/" + m.Slug + "?clave=" + secret
+}
+
+// HandleTournaments lists the tournaments.
+func (s server) HandleTournaments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		tournaments, err := s.store.ListTournaments()
+		if err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		render(w, r, tournamentsTemplate, tournaments)
+	}
+}
+
+func (s server) HandleTournamentForm() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render(w, r, tournamentFormTemplate, newTournamentForm())
+	}
+}
+
+// HandleTournament renders the bracket on GET and creates a tournament on
+// POST.
+func (s server) HandleTournament() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			s.handleGetTournament(w, r)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			s.handleCreateTournament(w, r)
+			return
+		}
+
+		http.Error(w, "method not supported", http.StatusBadRequest)
+	}
+}
+
+// handleCreateTournament registers the teams sent one per line and redirects
+// to the bracket. The browser keeps the edit secret like for a match.
+func (s server) handleCreateTournament(w http.ResponseWriter, r *http.Request) {
+	form := newTournamentForm()
+	form.Name = r.PostFormValue("name")
+	form.Teams = r.PostFormValue("teams")
+
+	format, err := parseTournamentFormat(r.PostFormValue("format"))
+	if err != nil {
+		form.Error = "Formato desconocido."
+		w.WriteHeader(http.StatusBadRequest)
+		render(w, r, tournamentFormTemplate, form)
+		return
+	}
+	target, err := formParseTargetScore(r)
+	if err != nil {
+		form.Error = "Los puntos para ganar deben ser un número positivo."
+		w.WriteHeader(http.StatusBadRequest)
+		render(w, r, tournamentFormTemplate, form)
+		return
+	}
+	rules, err := formParseRuleSet(r)
+	if err != nil {
+		form.Error = "Reglas desconocidas."
+		w.WriteHeader(http.StatusBadRequest)
+		render(w, r, tournamentFormTemplate, form)
+		return
+	}
+
+	t := NewTournament(
+		TournamentWithName(form.Name),
+		TournamentWithFormat(format),
+		TournamentWithTeams(strings.Split(form.Teams, "\n")...),
+		TournamentWithRuleSet(rules),
+		TournamentWithTargetScore(target),
+		TournamentWithOwner(currentUserID(r)),
+	)
+	err = s.store.CreateTournament(&t)
+	if err != nil {
+		form.Error = "Hace falta un nombre y al menos dos equipos distintos."
+		w.WriteHeader(http.StatusBadRequest)
+		render(w, r, tournamentFormTemplate, form)
+		return
+	}
+	setEditCookie(w, t.Slug, t.EditSecret)
+	http.Redirect(w, r, t.Slug, http.StatusSeeOther)
+}
+
+// handleGetTournament renders the bracket. As on the match page, a secret
+// sent as clave in the query is remembered in a cookie.
+func (s server) handleGetTournament(w http.ResponseWriter, r *http.Request) {
+	t, err := s.store.GetTournamentBySlug(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if t.ID == 0 {
+		http.Error(w, "tournament not found", http.StatusNotFound)
+		return
+	}
+
+	page := bracketPage{tournament: t}
+	secret := editSecret(r)
+	err = s.store.CheckTournamentEditAccess(t.ID, secret, currentUserID(r))
+	switch err.(type) {
+	case nil:
+		if r.URL.Query().Get("clave") != "" {
+			setEditCookie(w, t.Slug, secret)
+		}
+		page.Editable = true
+		page.EditSecret = secret
+	case *EditForbiddenError:
+	default:
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, tournamentTemplate, page)
+}
@@ -0,0 +1,50 @@
+package dominocount
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// user is a club member with an account. Matches created while logged in are
+// owned by the user, who can edit them from any device.
+type user struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MinPasswordLength is the shortest password accepted on signup.
+const MinPasswordLength = 8
+
+// MaxPasswordLength is the longest password bcrypt can hash, in bytes.
+const MaxPasswordLength = 72
+
+// sessionDuration is how long a login lasts.
+const sessionDuration = 30 * 24 * time.Hour
+
+// hashPassword returns the bcrypt hash stored for password.
+func hashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", errors.New("password is too short")
+	}
+	if len(password) > MaxPasswordLength {
+		return "", &PasswordTooLongError{}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// normalizeUserName trims the name and checks it is usable.
+func normalizeUserName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("user name cannot be empty")
+	}
+	return name, nil
+}
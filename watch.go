@@ -0,0 +1,69 @@
+package dominocount
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleWatch shows the spectator view of the match shared with the token.
+// Edits sent to a spectator link are refused.
+func (s server) HandleWatch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			m, ok := s.watchedMatch(w, r)
+			if !ok {
+				return
+			}
+			render(w, r, watchTemplate, m)
+			return
+		}
+		if r.Method == http.MethodPatch {
+			s.handlePatchMatch(w, r)
+			return
+		}
+		http.Error(w, "method not supported", http.StatusBadRequest)
+	}
+}
+
+// HandleWatchEvents streams the spectator table of the match shared with the
+// token.
+func (s server) HandleWatchEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not supported", http.StatusBadRequest)
+			return
+		}
+		m, ok := s.watchedMatch(w, r)
+		if !ok {
+			return
+		}
+		s.streamMatch(w, r, m.Id, watchTableTemplate)
+	}
+}
+
+// watchedMatch returns the match of the spectator token in the path. When
+// there is none the error is already written and ok is false.
+func (s server) watchedMatch(w http.ResponseWriter, r *http.Request) (m *match, ok bool) {
+	m, err := s.store.GetMatchByWatchToken(mux.Vars(r)["token"])
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if m.Id == 0 {
+		http.Error(w, "Match Not Found", http.StatusNotFound)
+		return nil, false
+	}
+	return m, true
+}
+
+// spectatorToken returns the spectator token a request came through, from
+// the /watch path or the X-Watch-Token header the spectator page sends with
+// its requests. It is empty for requests made by the scorekeeper.
+func spectatorToken(r *http.Request) string {
+	token := mux.Vars(r)["token"]
+	if token == "" {
+		token = r.Header.Get("X-Watch-Token")
+	}
+	return token
+}
@@ -0,0 +1,112 @@
+package dominocount_test
+
+import (
+	"dominocount"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWatchHandlerRendersReadOnlyView(t *testing.T) {
+	t.Parallel()
+
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch(dominocount.MatchWithTeam1Name("foo"), dominocount.MatchWithTeam2Name("bar"))
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddPointsByID(m.Id, 42, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/watch/"+m.WatchToken, nil)
+	req = mux.SetURLVars(req, map[string]string{"token": m.WatchToken})
+	server.HandleWatch()(rec, req)
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("want status 200 OK, got %d", res.StatusCode)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(body)
+	if !strings.Contains(got, "42") {
+		t.Errorf("want the score on the spectator view\nGot:\n%s", got)
+	}
+	for _, control := range []string{"sumar puntos", "editar", "deshacer", "borrar juego"} {
+		if strings.Contains(got, control) {
+			t.Errorf("want no %q control on the spectator view", control)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/watch/nope", nil)
+	req = mux.SetURLVars(req, map[string]string{"token": "nope"})
+	server.HandleWatch()(rec, req)
+	if rec.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("want status 404 for an unknown token, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestMatchHandlerRefusesEditsThroughSpectatorTokens(t *testing.T) {
+	t.Parallel()
+
+	tempDB := t.TempDir() + t.Name() + ".db"
+	store, err := dominocount.OpenSQLiteStore(tempDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dominocount.NewMatch()
+	err = store.CreateMatch(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := dominocount.NewServer(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/watch/"+m.WatchToken, strings.NewReader("team1_points=20&team2_points=0"))
+	req = mux.SetURLVars(req, map[string]string{"token": m.WatchToken})
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	server.HandleWatch()(rec, req)
+	if rec.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("want status 403 patching a spectator link, got %d", rec.Result().StatusCode)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPatch, "/match/"+m.Slug, strings.NewReader("team1_points=20&team2_points=0"))
+	req = mux.SetURLVars(req, map[string]string{"id": m.Slug})
+	req.Header.Set("X-Edit-Secret", m.EditSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Watch-Token", m.WatchToken)
+	server.HandleMatch()(rec, req)
+	if rec.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("want status 403 patching from a spectator page, got %d", rec.Result().StatusCode)
+	}
+
+	got, err := store.GetMatchByID(m.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Score1 != 0 {
+		t.Errorf("want score untouched by spectators, got %d", got.Score1)
+	}
+}